@@ -0,0 +1,108 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"time"
+)
+
+// Op identifies the kind of cache operation recorded in the access log.
+type Op int
+
+const (
+	// OpGet is a read of a single key.
+	OpGet Op = iota
+
+	// OpSet is a write of a single key.
+	OpSet
+
+	// OpRemove is an explicit removal of a single key.
+	OpRemove
+)
+
+// String returns the name of the operation.
+func (op Op) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	case OpRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// OpRecord describes a single cache operation kept in the access log.
+type OpRecord struct {
+	// Op is the kind of operation.
+	Op Op
+
+	// Key is the key the operation was applied to.
+	Key string
+
+	// Time is when the operation happened.
+	Time time.Time
+
+	// Hit reports whether the key was found. It is always false for OpSet.
+	Hit bool
+}
+
+// accessLog is a fixed-size ring buffer of the most recent operations.
+type accessLog struct {
+	mut     sync.Mutex
+	records []OpRecord
+	next    int
+	full    bool
+}
+
+// newAccessLog creates an access log holding the last size operations.
+func newAccessLog(size int) *accessLog {
+	return &accessLog{records: make([]OpRecord, size)}
+}
+
+// add appends a record, overwriting the oldest one once the ring is full.
+func (l *accessLog) add(record OpRecord) {
+	l.mut.Lock()
+	l.records[l.next] = record
+	l.next++
+	if l.next == len(l.records) {
+		l.next = 0
+		l.full = true
+	}
+	l.mut.Unlock()
+}
+
+// snapshot returns a copy of the records, oldest first.
+func (l *accessLog) snapshot() []OpRecord {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if !l.full {
+		return append([]OpRecord(nil), l.records[:l.next]...)
+	}
+	out := make([]OpRecord, 0, len(l.records))
+	out = append(out, l.records[l.next:]...)
+	return append(out, l.records[:l.next]...)
+}
+
+// logOp records an operation if the access log is enabled.
+func (m *CacheManager) logOp(op Op, key string, hit bool) {
+	if m.accessLog == nil {
+		return
+	}
+	m.accessLog.add(OpRecord{Op: op, Key: key, Time: time.Now(), Hit: hit})
+}
+
+// RecentOps returns the most recent cache operations, oldest first, up to
+// Config.AccessLogSize of them. It returns nil if the access log is disabled.
+func (m *CacheManager) RecentOps() []OpRecord {
+	if m.accessLog == nil {
+		return nil
+	}
+	return m.accessLog.snapshot()
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestRecentOps checks that the access log keeps the last AccessLogSize
+// operations, oldest first, with their hits, and is nil when disabled.
+func TestRecentOps(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, AccessLogSize: 3})
+	defer m.Close()
+	m.Set("a", 1, 1)
+	m.Get("a")
+	m.Get("b")
+	m.Remove("a")
+
+	var got []string
+	for _, r := range m.RecentOps() {
+		got = append(got, fmt.Sprintf("%s %s %v", r.Op, r.Key, r.Hit))
+	}
+	if want := "[get a true get b false remove a true]"; fmt.Sprint(got) != want {
+		t.Fatalf("RecentOps() = %v, want %s", got, want)
+	}
+
+	off := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer off.Close()
+	off.Set("a", 1, 1)
+	if ops := off.RecentOps(); ops != nil {
+		t.Fatalf("RecentOps() = %v without an access log, want nil", ops)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// Entry is a value to store with SetMany, with the size and TTL that would be
+// passed to SetTTL.
+type Entry struct {
+	Value interface{}
+	Size  uint64
+	TTL   time.Duration
+}
+
+// SetMany stores every entry of entries under its key. Entries are grouped by
+// shard so that each shard is locked once per batch rather than once per key.
+// Each entry is stored as with SetTTL: a zero Size uses the value's CacheCost,
+// a TTL of zero or less makes the entry never expire, and an entry whose size
+// exceeds MaxCost is rejected and removes the entry previously stored under
+// its key. Unlike SetTTL, entries are not coalesced; a write of one of the
+// keys still buffered by WriteCoalesceWindow is discarded. SetMany does
+// nothing while the cache is draining.
+func (m *CacheManager) SetMany(entries map[string]Entry) {
+	if m.draining.Load() || len(entries) == 0 {
+		return
+	}
+
+	type write struct {
+		key    string
+		val    interface{}
+		size   uint64
+		expiry int64
+		ttl    time.Duration
+	}
+	writes := make([]write, 0, len(entries))
+	sizes := make(map[string]uint64, len(entries))
+	var total uint64
+	for key, e := range entries {
+		m.dropPending(key)
+		m.logOp(OpSet, key, false)
+		val, size := m.encode(key, e.Value, m.costOf(e.Value, e.Size))
+		if !m.oversized(size) {
+			sizes[key] = size
+			total += size
+		}
+		writes = append(writes, write{key, val, size, expiryFor(e.TTL), e.TTL})
+	}
+	m.reserveMany(sizes, total)
+
+	m.poolMut.RLock()
+	groups := make([][]write, len(m.pool))
+	for _, w := range writes {
+		i := m.shardIndex(w.key)
+		groups[i] = append(groups[i], w)
+	}
+
+	now := time.Now().Unix()
+	var pending []removal
+	for i, shard := range m.pool {
+		if len(groups[i]) == 0 {
+			continue
+		}
+		shard.lock()
+		for _, w := range groups[i] {
+			if m.oversized(w.size) {
+				if node, ok := shard.lookup(w.key, now); ok && !node.readOnly {
+					shard.deleteNode(node, removalRemoved)
+				}
+				continue
+			}
+			m.storeLocked(shard, w.key, w.val, w.size, w.expiry, w.ttl, now, nil)
+		}
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+	}
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+}
+
+// GetMany looks up keys and returns the values of those found and unexpired,
+// keyed by key; missing and expired keys are left out. Lookups are grouped by
+// shard so that each shard is locked once, and found entries are promoted in
+// the LRU as with Get. See GetMultiPartition to also learn which keys missed.
+func (m *CacheManager) GetMany(keys []string) map[string]interface{} {
+	hits, _ := m.GetMultiPartition(keys)
+	return hits
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSetManyGetMany stores a batch over existing entries and checks that
+// GetMany returns every value written, old or new, with the TTL it was
+// written with, and leaves out missing and expired keys.
+func TestSetManyGetMany(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 100})
+	defer m.Close()
+	m.Set("kept", "old", 1)
+	m.Set("replaced", "old", 1)
+
+	m.SetMany(map[string]Entry{
+		"replaced": {Value: "new", Size: 1, TTL: time.Hour},
+		"forever":  {Value: 1, Size: 1},
+		"expired":  {Value: 2, Size: 1, TTL: time.Hour},
+	})
+	withNode(t, m, "expired", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+
+	got := m.GetMany([]string{"kept", "replaced", "forever", "expired", "missing"})
+	want := map[string]interface{}{"kept": "old", "replaced": "new", "forever": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetMany = %v, want %v", got, want)
+	}
+	if ttl, ok := m.TTL("replaced"); !ok || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL(replaced) = %v, %v; want about an hour", ttl, ok)
+	}
+	if ttl, ok := m.TTL("forever"); !ok || ttl != NoExpiry {
+		t.Errorf("TTL(forever) = %v, %v; want NoExpiry", ttl, ok)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSetManyOverwritesWithinBudget fills a cache to MaxCost and overwrites
+// every entry with a batch of the same sizes, which must not evict anything:
+// only what the batch adds to the entries it replaces counts against MaxCost.
+func TestSetManyOverwritesWithinBudget(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 100, MaxCost: 20})
+	defer m.Close()
+	entries := make(map[string]Entry)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		m.Set(key, i, 2)
+		entries[key] = Entry{Value: i * 10, Size: 2}
+	}
+
+	m.SetMany(entries)
+	if e := m.Stats().Evictions; e != 0 {
+		t.Fatalf("%d evictions overwriting a full cache with equal sizes, want 0", e)
+	}
+	if c := m.Cost(); c != 20 {
+		t.Fatalf("Cost() = %d, want 20", c)
+	}
+	for i := 0; i < 10; i++ {
+		if v, ok := m.Peek(fmt.Sprintf("key%d", i)); !ok || v != i*10 {
+			t.Errorf("Peek(key%d) = %v, %v; want %d, true", i, v, ok, i*10)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// BenchmarkHotShardP99 runs a mix of reads and writes from many parallel
+// goroutines with every key in one shard, with FairLocking off and on, and
+// reports the 99th percentile latency of Set, which is what contention on a
+// hot shard's lock shows up in.
+func BenchmarkHotShardP99(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		fair bool
+	}{
+		{"rwmutex", false},
+		{"fair", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			m := New(&Config{
+				ShardCap:    16,
+				NodeCap:     1 << 16,
+				ShardFunc:   func(string, int) int { return 0 },
+				FairLocking: bc.fair,
+			})
+			defer m.Close()
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key%d", i)
+				m.Set(keys[i], i, 1)
+			}
+
+			var mu sync.Mutex
+			var latencies []time.Duration
+			b.SetParallelism(32)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var local []time.Duration
+				for i := 0; pb.Next(); i++ {
+					key := keys[i%len(keys)]
+					if i%4 != 0 {
+						m.Get(key)
+						continue
+					}
+					start := time.Now()
+					m.Set(key, i, 1)
+					local = append(local, time.Since(start))
+				}
+				mu.Lock()
+				latencies = append(latencies, local...)
+				mu.Unlock()
+			})
+			b.StopTimer()
+
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			p99 := latencies[len(latencies)*99/100]
+			b.ReportMetric(float64(p99.Nanoseconds()), "p99-ns/op")
+		})
+	}
+}
+
+// BenchmarkBulkRemove removes half the entries of a heap-ordered shard,
+// either with RemovePrefix, which rebuilds the eviction heap once, or with a
+// Remove per key, which maintains the heap after every removal.
+func BenchmarkBulkRemove(b *testing.B) {
+	const entries = 50_000
+	for _, bc := range []struct {
+		name   string
+		remove func(m *CacheManager)
+	}{
+		{"deferred", func(m *CacheManager) { m.RemovePrefix("drop:") }},
+		{"per-key", func(m *CacheManager) {
+			for i := 0; i < entries; i += 2 {
+				m.Remove(fmt.Sprintf("drop:%d", i))
+			}
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				m := New(&Config{ShardCap: 1, NodeCap: entries, Policy: PolicyLFU})
+				for i := 0; i < entries; i++ {
+					prefix := "keep:"
+					if i%2 == 0 {
+						prefix = "drop:"
+					}
+					m.Set(fmt.Sprintf("%s%d", prefix, i), i, 1)
+				}
+				b.StartTimer()
+
+				bc.remove(m)
+
+				b.StopTimer()
+				m.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// BenchmarkSetMany stores a batch of entries with one SetMany, which locks
+// each shard once, or with a SetTTL per entry.
+func BenchmarkSetMany(b *testing.B) {
+	entries := make(map[string]Entry, 1000)
+	for i := 0; i < 1000; i++ {
+		entries[fmt.Sprintf("key%d", i)] = Entry{Value: i, Size: 1, TTL: time.Hour}
+	}
+
+	b.Run("SetMany", func(b *testing.B) {
+		m := New(&Config{ShardCap: 16, NodeCap: 1000})
+		defer m.Close()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			m.SetMany(entries)
+		}
+	})
+	b.Run("looped", func(b *testing.B) {
+		m := New(&Config{ShardCap: 16, NodeCap: 1000})
+		defer m.Close()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			for key, e := range entries {
+				m.SetTTL(key, e.Value, e.Size, e.TTL)
+			}
+		}
+	})
+}
+
+// removeNodeByScan is the removal EvictionHeap.RemoveNode replaced: a linear
+// search for the node followed by a rebuild of the whole heap.
+func removeNodeByScan(eh *EvictionHeap, node *Nodes) {
+	for i, n := range *eh {
+		if n == node {
+			*eh = append((*eh)[:i], (*eh)[i+1:]...)
+			for j := i; j < len(*eh); j++ {
+				(*eh)[j].heapIndex = j
+			}
+			heap.Init(eh)
+			return
+		}
+	}
+}
+
+// BenchmarkEvictionHeapRemove removes random nodes from a heap of one
+// million nodes, pushing each back so that the heap keeps its size, either by
+// heap index or by the linear scan and rebuild RemoveNode used before.
+func BenchmarkEvictionHeapRemove(b *testing.B) {
+	const size = 1_000_000
+	for _, bc := range []struct {
+		name   string
+		remove func(eh *EvictionHeap, node *Nodes)
+	}{
+		{"index", (*EvictionHeap).RemoveNode},
+		{"scan", removeNodeByScan},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			nodes := make([]*Nodes, size)
+			eh := make(EvictionHeap, 0, size)
+			for i := range nodes {
+				nodes[i] = &Nodes{lastUsed: rng.Int63n(1 << 20), seq: uint64(i)}
+				heap.Push(&eh, nodes[i])
+			}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				node := nodes[rng.Intn(size)]
+				bc.remove(&eh, node)
+				heap.Push(&eh, node)
+			}
+		})
+	}
+}
+
+// BenchmarkPolicyMemory fills a cache under the list-ordered LRU, CLOCK and a
+// heap-ordered policy, and reports the bytes allocated per entry. Run it with
+// -benchmem to see the allocations of each fill as well.
+func BenchmarkPolicyMemory(b *testing.B) {
+	const entries = 10_000
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	for _, bc := range []struct {
+		name   string
+		policy Policy
+	}{
+		{"LRU", PolicyLRU},
+		{"Clock", PolicyClock},
+		{"LFU-heap", PolicyLFU},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			for n := 0; n < b.N; n++ {
+				m := New(&Config{ShardCap: 1, NodeCap: entries, Policy: bc.policy})
+				for i, key := range keys {
+					m.Set(key, i, 1)
+				}
+				m.Close()
+			}
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*entries), "B/entry")
+		})
+	}
+}
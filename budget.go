@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+// reserve makes room for size more units of cost by evicting the coldest
+// entries of the whole cache until the total cost plus size fits within
+// MaxCost, or nothing more can be evicted. Writes call it before taking a
+// shard lock, and writes whose final size is only known under the lock call
+// it with a size of zero once the lock is released. The caller must not hold
+// any shard lock or poolMut.
+func (m *CacheManager) reserve(size uint64) {
+	for m.cost.Load()+size > m.maxCost {
+		if !m.evictColdest() {
+			return
+		}
+	}
+}
+
+// reserveFor is reserve for a write of size under key, counting only what the
+// write adds to the entry it replaces. That entry is only looked up when the
+// cache could not take the whole size otherwise.
+func (m *CacheManager) reserveFor(key string, size uint64) {
+	if m.cost.Load()+size <= m.maxCost {
+		return
+	}
+	shard := m.rlockKey(key)
+	if node, ok := shard.pool[key]; ok {
+		size -= min(size, node.nodeSize)
+	}
+	m.runlockKey(shard)
+	m.reserve(size)
+}
+
+// reserveMany is reserveFor for a batch writing sizes[key] under each key,
+// total units of cost in all: the entries the batch replaces are looked up,
+// each shard read-locked once, only when the cache could not take the whole
+// total otherwise.
+func (m *CacheManager) reserveMany(sizes map[string]uint64, total uint64) {
+	if m.cost.Load()+total <= m.maxCost {
+		return
+	}
+	keys := make([]string, 0, len(sizes))
+	for key := range sizes {
+		keys = append(keys, key)
+	}
+
+	m.poolMut.RLock()
+	for shard, group := range m.groupByShard(keys) {
+		shard.mut.RLock()
+		for _, key := range group {
+			if node, ok := shard.pool[key]; ok {
+				total -= min(sizes[key], node.nodeSize)
+			}
+		}
+		shard.mut.RUnlock()
+	}
+	m.poolMut.RUnlock()
+	m.reserve(total)
+}
+
+// evictColdest evicts the node that the shards' eviction policies rank
+// coldest across the whole cache and reports whether a node was evicted.
+// Each shard proposes its own victim through peekVictim, so that the shards
+// not evicted from keep their state, such as PolicyClock's reference bits,
+// and only the chosen shard evicts. The victims are compared with the same
+// ordering the eviction heap uses, so that pinned nodes and 2Q's protected
+// nodes are spared in favour of others wherever they live. poolMut
+// stays read-locked while shards are touched, so that dynamic sharding never
+// sees a shard mid-eviction.
+func (m *CacheManager) evictColdest() bool {
+	m.poolMut.RLock()
+	var (
+		best      *NodeShards
+		candidate Nodes
+		pending   []removal
+	)
+	for _, shard := range m.pool {
+		shard.lock()
+		if node := shard.peekVictim(); node != nil {
+			if best == nil || evictsBefore(node, &candidate) {
+				best = shard
+				candidate = node.ranking()
+			}
+		}
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+	}
+
+	evicted := false
+	if best != nil {
+		best.lock()
+		evicted = best.evict() != nil
+		pending = append(pending, best.takePending()...)
+		best.unlock()
+	}
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+	return evicted
+}
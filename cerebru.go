@@ -6,7 +6,10 @@
 package cerebru
 
 import (
-	"container/heap"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/bluespada/cerebru/internal/crypt"
@@ -24,7 +27,11 @@ type Config struct {
 	// EnableDynamicSharding indicates whether dynamic sharding is enabled.
 	// Dynamic sharding automatically adjusts the number of shards
 	// based on usage patterns to optimize performance and resource
-	// utilization.
+	// utilization. The pool starts with four shards, grows by one when a
+	// shard is nearly full, and shrinks by one once the entries would fill
+	// one shard fewer to no more than a quarter of NodeCap. It never
+	// shrinks below four shards, nor below the size EnsureShards and
+	// Prewarm last grew it to.
 	EnableDynamicSharding bool
 
 	// ShardCap specifies the maximum number of shards allowed.
@@ -44,202 +51,1520 @@ type Config struct {
 	// arbitrary units. It helps manage resource consumption and should
 	// be set to a non-negative value. Use with caution, as this feature
 	// is experimental and may change in future versions.
+	// The budget is global: the cost of all entries, across every shard,
+	// is tracked together, and a write that would push the total over
+	// MaxCost first evicts the coldest entries of the whole cache, in the
+	// order their shards' eviction policies pick them, until it fits. A
+	// single entry larger than MaxCost is rejected instead of being stored.
 	// default:512
 	MaxCost uint64
+
+	// OnShardChange, if set, is called after dynamic sharding adds or
+	// removes shards, with the change in shard count (positive when
+	// shards were added, negative when removed) and the new total. It is
+	// called without any internal lock held, so it may query the manager.
+	OnShardChange func(delta int, total int)
+
+	// CloseOnEvict closes values that implement io.Closer when they leave
+	// the cache through eviction, expiry or removal. Close is called once
+	// per entry, after the shard lock has been released.
+	CloseOnEvict bool
+
+	// OnEvict, if set, is called with the key and value of every entry
+	// evicted by capacity or cost pressure, and OnExpire with those of every
+	// entry removed because its expiry passed, whether it was found expired
+	// on read, swept by the cleaner or dropped during a rebalance. Neither is
+	// called for entries removed explicitly. Each is called once per entry,
+	// after the shard lock has been released, so it may use the cache. The
+	// value is passed decoded, and a lazy value is computed first.
+	OnEvict  func(key string, value interface{})
+	OnExpire func(key string, value interface{})
+
+	// Policy selects how shards choose which entry to evict when they
+	// are over capacity or over their cost budget.
+	// default:PolicyLRU
+	Policy Policy
+
+	// CustomPolicy, if set, replaces Policy with an EvictionPolicy of the
+	// caller's own. It is called once for every shard, and again whenever
+	// a shard is emptied, such as by Clear or a rebalance, and must return
+	// a new instance each time, since every shard owns its policy. A nil
+	// result falls back to Policy.
+	CustomPolicy func() EvictionPolicy
+
+	// StatsInterval is how often OnStats receives a snapshot of the cache
+	// statistics. Both StatsInterval and OnStats must be set to enable the
+	// periodic report, which runs on a single background goroutine.
+	StatsInterval time.Duration
+
+	// OnStats receives a Stats snapshot every StatsInterval.
+	OnStats func(Stats)
+
+	// StrictCost makes MaxCost a hard cap on the total cost of the cache.
+	// Each write reserves its cost from the budget atomically before
+	// inserting, evicting entries of its shard until the reservation fits,
+	// so that concurrent writes to different shards never take the cache
+	// over MaxCost, even momentarily, at the cost of extra latency per
+	// write. A write replacing an entry only reserves what it adds to that
+	// entry's cost. A write that cannot make room, because the remaining
+	// entries are pinned, is rejected and leaves the entry stored under its
+	// key as it was; SetChecked and Tx.Set report it with ErrCostExceeded.
+	// By default entries are inserted first and the budget is restored by
+	// evicting afterwards.
+	StrictCost bool
+
+	// EncodeValues stores every value as bytes produced by Codec, decoding
+	// it again on read. The size of an encoded entry is its exact encoded
+	// length, replacing the size passed by the caller. Values that fail to
+	// encode are stored as they are.
+	EncodeValues bool
+
+	// Codec encodes and decodes values when EncodeValues is enabled.
+	// default:GobCodec
+	Codec Codec
+
+	// CleanerBudget is the maximum number of expired entries the cleaner
+	// removes from a shard per sweep. Remaining expired entries are left for
+	// the following sweeps, which keeps each sweep's lock hold time short
+	// when many entries expire at once. Zero means no limit.
+	CleanerBudget int
+
+	// AccessLogSize enables an in-memory ring of the last AccessLogSize
+	// Get, Set and Remove operations, retrievable with RecentOps for
+	// post-incident forensics. Zero disables the log at no cost.
+	AccessLogSize int
+
+	// CleanerYieldUnderLoad makes the cleaner skip a sweep of a shard while
+	// that shard is taking writes at a high rate, so that the sweep does not
+	// compete with the writes for the shard lock. The sweep is retried on
+	// the next tick, and runs regardless after a few consecutive skips so
+	// that expired entries are still reclaimed under sustained load.
+	CleanerYieldUnderLoad bool
+
+	// TTLRules assigns default TTLs by key prefix. Set gives a key the TTL
+	// of the first rule whose Prefix it starts with; keys matching no rule
+	// keep Set's usual expiry. Calls that take an explicit TTL, such as
+	// SetTTL, ignore the rules.
+	TTLRules []TTLRule
+
+	// MaxGoroutines caps the number of goroutines the cache runs in the
+	// background. When the cap leaves too few goroutines for a cleaner per
+	// shard, the shards without one are swept together by a single shared
+	// cleaner, and background work that cannot get a goroutine at all,
+	// such as the OnStats report, is not started. The shared cleaner and
+	// the stats report are started before the per-shard cleaners.
+	// Zero means no limit.
+	MaxGoroutines int
+
+	// ShardFunc, if set, chooses the shard of every key instead of the
+	// internal hash. It receives the key and the current number of shards
+	// and returns a shard index; results outside [0, n) are wrapped into
+	// range. Keys are stored in the shard ShardFunc selects, evicting from
+	// it when it is full, which makes placement deterministic for tests and
+	// for callers that want to co-locate related keys.
+	// default:nil, keys are spread by hashing
+	ShardFunc func(key string, n int) int
+
+	// CompressMinSize compresses values whose size is at least
+	// CompressMinSize with DEFLATE, decompressing them again on read.
+	// Only byte slices, strings and values stored encoded because of
+	// EncodeValues are compressed, and only when that makes them smaller;
+	// the size of a compressed entry is its compressed length.
+	// Zero disables compression unless ShouldCompress is set.
+	CompressMinSize uint64
+
+	// ShouldCompress, if set, decides for each write whether the value is
+	// compressed, overriding CompressMinSize. It receives the key, the value
+	// as passed by the caller and its size. The same restrictions on the
+	// kinds of values that are compressed apply.
+	ShouldCompress func(key string, value interface{}, size uint64) bool
+
+	// WriteCoalesceWindow buffers Set and SetTTL calls for up to the given
+	// duration. Repeated writes of a key within a window replace each other,
+	// and only the latest one is applied to the cache when the window closes,
+	// which cuts lock traffic for keys updated many times per second. Get,
+	// GetOK, GetOrDefault and GetInto see buffered values, while other reads
+	// only see them once applied; Remove discards a buffered write of its key.
+	// Writes that read the entry under the shard lock, such as GetSet, Merge,
+	// Touch, Expire, Pin and transactions, apply the buffered write of their
+	// key first. Buffered writes are applied by a background goroutine; if
+	// MaxGoroutines leaves none for it, writes are not coalesced. Zero
+	// disables coalescing.
+	WriteCoalesceWindow time.Duration
+
+	// SlidingTTL makes reads through Get, GetOK, GetOrDefault, GetInto and
+	// GetMultiOrdered extend an entry's lifetime: each hit resets its
+	// expiry to now plus the TTL it was last given by SetTTL, Touch,
+	// Expire, GetAndTouch or TouchMulti. Entries without a TTL, including
+	// those made persistent with Persist, are unaffected.
+	SlidingTTL bool
+
+	// TTLJitter randomly shortens or lengthens the lifetime of each entry
+	// by up to this fraction of it, so that entries written together, or
+	// refreshed together by SlidingTTL, do not expire, and get recomputed,
+	// all at once. It applies to Set, SetTTL and the other writes that
+	// store a value with a TTL, and to every SlidingTTL refresh. With 0.1,
+	// an entry given a one hour TTL expires between 54 and 66 minutes
+	// after it is written or read. Each shard draws from its own random
+	// source, seeded from one source per cache. Expiries have a resolution
+	// of one second, and entries never expire early enough to be stale
+	// when written. Zero disables jitter.
+	TTLJitter float64
+
+	// RequireExplicitTTL forbids Set from falling back to its 12 hour
+	// default expiry: Set panics with ErrTTLRequired for any key that no
+	// TTLRule covers, so that writes without a deliberate lifetime are
+	// caught at the call site. SetTTL and the other calls that take a TTL
+	// are unaffected.
+	RequireExplicitTTL bool
+
+	// TrackLockHold records, for every shard, the longest time its write
+	// lock was held, as reported by MaxLockHold. It is meant for latency
+	// debugging, such as finding rebalances or eviction batches that stall
+	// other callers, and costs two clock reads per write lock when enabled.
+	TrackLockHold bool
+
+	// FairLocking replaces every shard's sync.RWMutex with a lock that
+	// bounds how long a caller, reader or writer, can be overtaken by
+	// callers that arrived after it: once the oldest waiter has waited
+	// about 100µs, the lock is handed over strictly in arrival order until
+	// the queue catches up. It is meant for hot shards whose p99 latency
+	// suffers from readers or writers being starved, and costs throughput
+	// while the lock is handed over, since each hand-over wakes a sleeping
+	// goroutine. BenchmarkHotShardP99 compares the two locks.
+	FairLocking bool
+
+	// SingleFlight deduplicates the loads GetLoad runs for missing keys.
+	// Setting it to a *singleflight.Group from golang.org/x/sync shares the
+	// deduplication with the rest of an application that already uses one.
+	// default:nil, the cache deduplicates loads itself
+	SingleFlight SingleFlight
+
+	// EvictHysteresis makes a write that takes a shard over its capacity
+	// evict a batch rather than a single entry: the shard is brought down
+	// to capacity * (1 - EvictHysteresis) entries, leaving headroom so that
+	// a cache running at capacity does not evict on every insert, and a hot
+	// key re-inserted right after its eviction is not evicted again at
+	// once. It applies to capacity, not to MaxCost, and at least one entry
+	// is always kept. Zero disables it; a typical value is 0.05.
+	EvictHysteresis float64
+
+	// MemoryPressureThreshold starts a background watcher that samples the
+	// process heap every MemoryPressureInterval and, whenever it exceeds
+	// MemoryPressureThreshold bytes, evicts the coldest entries until the
+	// cache's cost has dropped by the excess, independently of MaxCost. This
+	// assumes entry sizes are given in bytes. The watcher needs a goroutine
+	// and is not started if MaxGoroutines leaves none for it.
+	// Zero disables the watcher.
+	MemoryPressureThreshold uint64
+
+	// MemoryPressureInterval is how often the memory pressure watcher
+	// samples the heap.
+	// default:1s
+	MemoryPressureInterval time.Duration
+
+	// HeapInUse, if set, replaces the heap sampling of the memory pressure
+	// watcher. It returns the number of heap bytes in use, and lets the
+	// watcher follow an external signal such as a container's memory usage.
+	// default:nil, runtime.MemStats.HeapAlloc is used
+	HeapInUse func() uint64
+
+	// MinRebalanceInterval spaces out the shard scaling of dynamic sharding:
+	// once shards have been added or removed, and entries rebalanced, no
+	// further scaling happens until the interval has passed, however often
+	// the trigger condition is met, which keeps oscillating load from
+	// rebalancing continuously. Explicit calls such as EnsureShards and
+	// Rehash are not limited. Zero means no limit.
+	MinRebalanceInterval time.Duration
+
+	// CheckpointPath is the file the cache is checkpointed to. When set, New
+	// restores the entries of an existing checkpoint there, and, with a
+	// CheckpointInterval, a background goroutine writes a fresh checkpoint
+	// every interval and once more when the cache is closed. Checkpoints use
+	// the Snapshot format, so value types must be registered with
+	// RegisterType, and are written to a temporary file that is renamed into
+	// place, so a crash never leaves a partial checkpoint behind.
+	CheckpointPath string
+
+	// CheckpointInterval is how often the cache is checkpointed to
+	// CheckpointPath. Zero disables periodic checkpoints; Checkpoint can
+	// still be called explicitly.
+	CheckpointInterval time.Duration
 }
 
 // New creates a new instance of CacheManager based on the provided configuration options.
 // It initializes the cache manager with a specified number of shards and sets up the
-// jump consistent hashing (JCH) for shard management.
+// jump consistent hashing (JCH) for shard management. The configuration is copied,
+// so modifying it after New returns has no effect on the manager.
 func New(opt *Config) *CacheManager {
+	// Work on a copy so that later changes to the caller's Config
+	// cannot affect the manager.
+	cfg := *opt
+
 	var initialShards int
 	var defaultMaxCost uint64
 
-	if opt.MaxCost == 0 {
+	if cfg.MaxCost == 0 {
 		defaultMaxCost = 512 * UnitMB
 	} else {
-		defaultMaxCost = opt.MaxCost
+		defaultMaxCost = cfg.MaxCost
 	}
 
-	if opt.EnableDynamicSharding {
-		initialShards = 4
+	if cfg.EnableDynamicSharding {
+		initialShards = minShards
 	} else {
-		initialShards = opt.ShardCap
+		initialShards = cfg.ShardCap
 	}
 
 	manager := &CacheManager{
-		pool:                      make([]*NodeShards, 0, opt.ShardCap),
-		enableAutoCleaner:         opt.EnableCleaner,
-		enableDynamicShardScaling: opt.EnableDynamicSharding,
-		shardCap:                  opt.ShardCap,
-		nodeCap:                   opt.NodeCap,
-		jch:                       crypt.NewjCH(opt.ShardCap),
+		pool:                      make([]*NodeShards, 0, cfg.ShardCap),
+		enableAutoCleaner:         cfg.EnableCleaner,
+		enableDynamicShardScaling: cfg.EnableDynamicSharding,
+		shardCap:                  cfg.ShardCap,
+		nodeCap:                   cfg.NodeCap,
+		jch:                       crypt.NewjCH(cfg.ShardCap),
 		maxCost:                   defaultMaxCost,
+		onShardChange:             cfg.OnShardChange,
+		stats:                     &cacheStats{},
+		closeOnEvict:              cfg.CloseOnEvict,
+		onEvict:                   cfg.OnEvict,
+		onExpire:                  cfg.OnExpire,
+		hysteresis:                cfg.EvictHysteresis,
+		minRebalance:              cfg.MinRebalanceInterval,
+		minPool:                   minShards,
+		checkpointPath:            cfg.CheckpointPath,
+		policy:                    cfg.Policy,
+		customPolicy:              cfg.CustomPolicy,
+		done:                      make(chan struct{}),
+		strictCost:                cfg.StrictCost,
+		cleanerBudget:             cfg.CleanerBudget,
+		cleanerYield:              cfg.CleanerYieldUnderLoad,
+		ttlRules:                  append([]TTLRule(nil), cfg.TTLRules...),
+		maxGoroutines:             cfg.MaxGoroutines,
+		shardFunc:                 cfg.ShardFunc,
+		compressMinSize:           cfg.CompressMinSize,
+		shouldCompress:            cfg.ShouldCompress,
+		slidingTTL:                cfg.SlidingTTL,
+		ttlJitter:                 cfg.TTLJitter,
+		rng:                       rand.New(rand.NewSource(time.Now().UnixNano())),
+		requireTTL:                cfg.RequireExplicitTTL,
+		trackLockHold:             cfg.TrackLockHold,
+		fairLocking:               cfg.FairLocking,
+		flight:                    cfg.SingleFlight,
+	}
+
+	if cfg.AccessLogSize > 0 {
+		manager.accessLog = newAccessLog(cfg.AccessLogSize)
+	}
+
+	if manager.flight == nil {
+		manager.flight = &loadGroup{}
+	}
+
+	if cfg.EncodeValues {
+		manager.codec = cfg.Codec
+		if manager.codec == nil {
+			manager.codec = GobCodec{}
+		}
+	}
+
+	if cfg.EnableCleaner && cfg.MaxGoroutines > 0 {
+		manager.spawn(func() { manager.sharedCleaner(5 * time.Second) })
+	}
+
+	if cfg.WriteCoalesceWindow > 0 {
+		c := &coalescer{pending: make(map[string]pendingWrite)}
+		if manager.spawn(func() { manager.flushCoalesced(c, cfg.WriteCoalesceWindow) }) {
+			manager.coalescer = c
+		}
+	}
+
+	if cfg.StatsInterval > 0 && cfg.OnStats != nil {
+		manager.spawn(func() { manager.reportStats(cfg.StatsInterval, cfg.OnStats) })
 	}
 
+	if cfg.MemoryPressureThreshold > 0 {
+		interval := cfg.MemoryPressureInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		heapInUse := cfg.HeapInUse
+		if heapInUse == nil {
+			heapInUse = heapAlloc
+		}
+		manager.spawn(func() {
+			manager.watchMemory(interval, cfg.MemoryPressureThreshold, heapInUse)
+		})
+	}
+
+	// The background goroutines may already be running, so the pool is
+	// filled under its lock.
+	manager.poolMut.Lock()
 	for i := 0; i < initialShards; i++ {
 		manager.addShard()
 	}
+	manager.poolMut.Unlock()
+
+	if cfg.CheckpointPath != "" {
+		manager.restoreCheckpoint()
+		if cfg.CheckpointInterval > 0 {
+			manager.spawn(func() { manager.checkpointEvery(cfg.CheckpointInterval) })
+		}
+	}
 
 	return manager
 }
 
-// Set adds a key-value pair to the cache. If the key already exists, it updates the value.
-// If the cache is full, it finds the least loaded shard to store the new entry.
-// The entry is set to expire after 12 hours by default if it is newly created.
+// defaultTTL is the time-to-live given to entries stored by Set.
+const defaultTTL = 12 * time.Hour
+
+// Set stores val under key, replacing any entry already stored there, in the
+// shard the key belongs to, which evicts to make room if it is full. The entry
+// expires after 12 hours, or after the TTL of the TTLRule matching key, and a
+// size of zero is replaced by val's CacheCost if it implements Coster. An
+// entry larger than MaxCost is rejected and removes the entry previously
+// stored under key. Set is subject to RequireExplicitTTL, WriteCoalesceWindow
+// and StrictCost, leaves a read-only entry stored by SetReadOnly untouched, as
+// SetChecked reports, and does nothing while the cache is draining.
 func (m *CacheManager) Set(key string, val interface{}, size uint64) {
+	if m.requireTTL {
+		if _, ok := m.ruleTTL(key); !ok {
+			panic(fmt.Errorf("%w: Set(%q)", ErrTTLRequired, key))
+		}
+	}
+	if m.coalesce(key, pendingWrite{val: val, size: size}) {
+		return
+	}
+	m.setDefault(key, val, size, nil)
+}
+
+// setDefault implements Set and SetWeighted without write coalescing,
+// choosing the expiry from the TTLRules or the default TTL.
+func (m *CacheManager) setDefault(key string, val interface{}, size uint64, weight *uint64) error {
+	if ttl, ok := m.ruleTTL(key); ok {
+		return m.set(key, val, size, expiryFor(ttl), ttl, weight)
+	}
+	return m.set(key, val, size, expiryFor(defaultTTL), 0, weight)
+}
+
+// SetTTL stores val under key like Set, but expires the entry after ttl,
+// ignoring the TTLRules and RequireExplicitTTL. A ttl of zero or less makes
+// the entry never expire.
+func (m *CacheManager) SetTTL(key string, val interface{}, size uint64, ttl time.Duration) {
+	if m.coalesce(key, pendingWrite{val: val, size: size, ttl: ttl, hasTTL: true}) {
+		return
+	}
+	m.set(key, val, size, expiryFor(ttl), ttl, nil)
+}
+
+// set is the single write path behind Set, SetTTL and SetWeighted. It stores
+// val under key with the given expiry, a Unix timestamp where zero means
+// never, and records ttl on the node for sliding expiry. The size is resolved
+// with costOf and replaced by the stored length of an encoded or compressed
+// value. It returns ErrReadOnly if key holds a read-only entry.
+func (m *CacheManager) set(key string, val interface{}, size uint64, expiry int64, ttl time.Duration, weight *uint64) error {
+	if m.draining.Load() {
+		return nil
+	}
+	m.logOp(OpSet, key, false)
+	size = m.costOf(val, size)
+	val, size = m.encode(key, val, size)
+	return m.store(key, val, size, expiry, ttl, weight)
+}
+
+// store writes an already encoded value under key with the given size and
+// the eviction weight pointed to by weight, or none if weight is nil. An
+// existing live entry is updated in place and promoted; an expired or stale one
+// is dropped and replaced by a new node, so the size accounting is the same
+// either way. A read-only entry is left untouched and ErrReadOnly returned.
+func (m *CacheManager) store(key string, val interface{}, size uint64, expiry int64, ttl time.Duration, weight *uint64) error {
+	if m.oversized(size) {
+		return m.discard(key)
+	}
+	m.reserveFor(key, size)
+
 	if m.enableDynamicShardScaling {
 		m.dynamicShardScaling()
 	}
 
-	hashVal := m.jch.Hash(key)
-	shardIndex := hashVal % uint64(len(m.pool))
-	shard := m.pool[shardIndex]
-
-	shard.mut.Lock()
-	if shard.size >= shard.capacity {
-		shard.mut.Unlock()
-		shard = m.findLeastLoadedShard()
-		shard.mut.Lock()
-	}
-
-	if node, exists := shard.pool[key]; exists {
-		if node.expiredAt >= 0 && node.expiredAt <= time.Now().Unix() {
-			shard.shardSize -= size
-			shard.removeNode(node)
-			delete(shard.pool, key)
-			shard.size--
-		} else {
-			node.Value = val
-			node.nodeSize = size
-			node.expiredAt = time.Now().Add(12 * time.Hour).Unix()
-			shard.mut.Unlock()
+	shard := m.lockKey(key)
+	err := m.storeLocked(shard, key, val, size, expiry, ttl, time.Now().Unix(), weight)
+	m.unlockKey(shard)
+	return err
+}
+
+// storeLocked writes an already encoded value that fits within MaxCost under
+// key in shard, updating an entry live at now in place or admitting a new
+// node. The entry is given the eviction weight pointed to by weight, or none
+// if weight is nil. A live read-only entry is left untouched and ErrReadOnly
+// returned, as is an entry for which StrictCost cannot make room, with
+// ErrCostExceeded. The caller must hold the shard lock.
+func (m *CacheManager) storeLocked(shard *NodeShards, key string, val interface{}, size uint64, expiry int64, ttl time.Duration, now int64, weight *uint64) error {
+	expiry = shard.spread(expiry, now)
+	var w uint64
+	if weight != nil {
+		w = *weight
+	}
+
+	if node, ok := shard.lookup(key, now); ok {
+		if node.readOnly {
+			return ErrReadOnly
+		}
+		if !shard.fits(node, size) {
+			return ErrCostExceeded
+		}
+		node.ttl = ttl
+		node.weight, node.weighted = w, weight != nil
+		node.expiredAt = expiry
+		shard.update(node, val, size)
+		return nil
+	}
+
+	if !shard.admit(&Nodes{
+		Key:       key,
+		Value:     val,
+		expiredAt: expiry,
+		ttl:       ttl,
+		nodeSize:  size,
+		weight:    w,
+		weighted:  weight != nil,
+	}) {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+// SetTTLNoLRU stores a pure TTL entry: it expires after ttl like an entry
+// stored with SetTTL, but takes no part in LRU bookkeeping. It is never moved
+// on access, never chosen for eviction by capacity or cost pressure, and is not
+// visited by RangeLRU, so entries such as rate-limit windows do not perturb the
+// eviction order. It still counts towards the shard's size and cost, and leaves
+// the cache only by expiring or being removed. Any entry already stored under
+// key is replaced, and the entry stays a pure TTL entry if it is written again
+// by other methods. A ttl of zero or less makes the entry never expire.
+// SetTTLNoLRU is never coalesced, and discards a write of key still buffered
+// by WriteCoalesceWindow. It does nothing while the cache is draining.
+func (m *CacheManager) SetTTLNoLRU(key string, val interface{}, size uint64, ttl time.Duration) {
+	if m.draining.Load() {
+		return
+	}
+	m.logOp(OpSet, key, false)
+	size = m.costOf(val, size)
+	val, size = m.encode(key, val, size)
+	if m.oversized(size) {
+		m.discard(key)
+		return
+	}
+	m.reserveFor(key, size)
+
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	m.dropPending(key)
+	now := time.Now().Unix()
+	if node, ok := shard.lookup(key, now); ok {
+		if node.readOnly || !shard.fits(node, size) {
 			return
 		}
+		shard.unlink(node)
+	}
+	shard.admit(&Nodes{
+		Key:       key,
+		Value:     val,
+		expiredAt: shard.spread(expiryFor(ttl), now),
+		ttl:       ttl,
+		nodeSize:  size,
+		noLRU:     true,
+	})
+}
+
+// Get retrieves the value associated with the given key from the cache.
+// If the key exists and has not expired, it returns the value; otherwise, it returns nil.
+// Use GetOK to tell a stored nil apart from a missing key.
+func (m *CacheManager) Get(key string) interface{} {
+	val, _ := m.GetOK(key)
+	return val
+}
+
+// GetOrDefault retrieves the value associated with the given key, or def if the
+// key is missing or has expired. An entry that legitimately holds nil returns nil.
+func (m *CacheManager) GetOrDefault(key string, def interface{}) interface{} {
+	if val, ok := m.GetOK(key); ok {
+		return val
 	}
+	return def
+}
+
+// GetOK retrieves the value associated with the given key and reports whether
+// the key existed and had not expired. Unlike Get, it distinguishes an entry
+// that holds nil from a missing one, so nil results can be cached. A live entry
+// is promoted in the LRU.
+func (m *CacheManager) GetOK(key string) (interface{}, bool) {
+	if m.closed.Load() {
+		return nil, false
+	}
+	if val, ok := m.pendingValue(key); ok {
+		m.logOp(OpGet, key, true)
+		m.stats.recordRead(true)
+		return val, true
+	}
+
+	shard := m.lockKey(key)
+	node, ok := shard.lookup(key, time.Now().Unix())
+	m.logOp(OpGet, key, ok)
+	if !ok {
+		m.unlockKey(shard)
+		m.stats.recordRead(false)
+		return nil, false
+	}
+	shard.moveToHead(node)
+	m.slide(shard, node)
+	val := node.Value
+	m.unlockKey(shard)
+
+	m.stats.recordRead(true)
+	return m.resolveValue(shard, node, val), true
+}
+
+// GetMultiOrdered retrieves the values of keys, returning them in the same order
+// as keys along with a found flag for each position. Duplicate keys are looked up
+// for every position they appear in. Lookups are grouped by shard so that each
+// shard is locked once, and found entries are promoted in the LRU as with Get.
+func (m *CacheManager) GetMultiOrdered(keys []string) ([]interface{}, []bool) {
+	values := make([]interface{}, len(keys))
+	found := make([]bool, len(keys))
+	if m.closed.Load() {
+		return values, found
+	}
+
+	m.poolMut.RLock()
+	groups := make(map[*NodeShards][]int)
+	for i, key := range keys {
+		shard := m.shardFor(key)
+		groups[shard] = append(groups[shard], i)
+	}
+
+	nodes := make([]*Nodes, len(keys))
+	shards := make([]*NodeShards, len(keys))
+	var pending []removal
+	for shard, positions := range groups {
+		now := time.Now().Unix()
+		shard.lock()
+		for _, i := range positions {
+			node, ok := shard.lookup(keys[i], now)
+			m.stats.recordRead(ok)
+			if !ok {
+				continue
+			}
+			shard.moveToHead(node)
+			m.slide(shard, node)
+			nodes[i], shards[i] = node, shard
+			values[i], found[i] = node.Value, true
+		}
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+	}
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+
+	for i, node := range nodes {
+		if node != nil {
+			values[i] = m.resolveValue(shards[i], node, values[i])
+		}
+	}
+	return values, found
+}
+
+// GetMultiPartition looks up keys in one pass, as GetMultiOrdered does, and
+// splits them for a cache-aside fill: hits maps every key found to its value,
+// and misses lists the keys that are missing or expired, in the order they
+// first appear in keys, ready to be fetched and backfilled. A key listed
+// several times appears once in either result.
+func (m *CacheManager) GetMultiPartition(keys []string) (hits map[string]interface{}, misses []string) {
+	values, found := m.GetMultiOrdered(keys)
 
-	newNode := &Nodes{
+	hits = make(map[string]interface{}, len(keys))
+	missed := make(map[string]struct{})
+	for i, key := range keys {
+		if found[i] {
+			hits[key] = values[i]
+			continue
+		}
+		if _, seen := missed[key]; !seen {
+			missed[key] = struct{}{}
+			misses = append(misses, key)
+		}
+	}
+	return hits, misses
+}
+
+// Merge stores val under key, or, if a live entry already exists, replaces it
+// with the result of merge(existing, val). The lookup and the write happen under
+// the shard lock, so concurrent Merges of the same key never lose an update.
+// size is the cost of the stored result; the entry keeps its current expiry, and
+// a newly created entry never expires. A result whose size exceeds MaxCost is
+// rejected like in Set. A write of key still buffered by WriteCoalesceWindow
+// is applied first, so that it is what merge sees as existing. merge runs with
+// the shard locked and must not call back into the cache. Merge does nothing
+// while the cache is draining.
+func (m *CacheManager) Merge(key string, val interface{}, size uint64, merge func(existing, incoming interface{}) interface{}) {
+	if m.draining.Load() {
+		return
+	}
+
+	defer m.reserve(0)
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	m.applyPending(shard, key)
+	if node, ok := shard.lookup(key, time.Now().Unix()); ok {
+		if node.readOnly {
+			return
+		}
+		existing := node.Value
+		if lv, isLazy := existing.(*lazyValue); isLazy {
+			existing, _ = lv.resolve()
+		}
+		merged := merge(m.decode(existing), val)
+		size = m.costOf(merged, size)
+		stored, size := m.encode(key, merged, size)
+		if m.oversized(size) {
+			shard.deleteNode(node, removalRemoved)
+			return
+		}
+		if shard.fits(node, size) {
+			shard.update(node, stored, size)
+		}
+		return
+	}
+
+	size = m.costOf(val, size)
+	val, size = m.encode(key, val, size)
+	if m.oversized(size) {
+		return
+	}
+	shard.admit(&Nodes{
 		Key:      key,
 		Value:    val,
 		nodeSize: size,
+	})
+}
+
+// GetSet stores val under key and returns the value it replaced, with existed
+// reporting whether a live entry was present. The lookup and the write happen
+// under the shard lock, so no other write can slip in between them. A replaced
+// entry keeps its current expiry, and a newly created entry never expires, as
+// with Merge. A value whose size exceeds MaxCost is rejected and the previous
+// entry is removed, but is still returned. A write of key still buffered by
+// WriteCoalesceWindow is applied first and returned as the previous entry.
+// GetSet does nothing, and reports no previous entry, while the cache is
+// draining.
+func (m *CacheManager) GetSet(key string, val interface{}, size uint64) (old interface{}, existed bool) {
+	if m.draining.Load() {
+		return nil, false
+	}
+	m.logOp(OpSet, key, false)
+	size = m.costOf(val, size)
+	val, size = m.encode(key, val, size)
+	if !m.oversized(size) {
+		m.reserveFor(key, size)
 	}
 
-	shard.shardSize += size
-	shard.addToHead(newNode)
-	shard.pool[key] = newNode
-	shard.size++
+	defer m.reserve(0)
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	m.applyPending(shard, key)
+	if node, ok := shard.lookup(key, time.Now().Unix()); ok {
+		old = node.Value
+		if lv, isLazy := old.(*lazyValue); isLazy {
+			old, _ = lv.resolve()
+		}
+		old = m.decode(old)
 
-	if shard.size > shard.capacity {
-		evicted := shard.removeTail()
-		if evicted != nil {
-			delete(shard.pool, evicted.Key)
-			shard.size--
+		if node.readOnly {
+			return old, true
+		}
+		if m.oversized(size) {
+			shard.deleteNode(node, removalRemoved)
+			return old, true
+		}
+		if shard.fits(node, size) {
+			shard.update(node, val, size)
 		}
+		return old, true
 	}
 
-	shard.mut.Unlock()
+	if m.oversized(size) {
+		return nil, false
+	}
+	shard.admit(&Nodes{
+		Key:      key,
+		Value:    val,
+		nodeSize: size,
+	})
+	return nil, false
 }
 
-// SetTTL adds a key-value pair to the cache with a specified time-to-live (TTL).
-// If the key already exists, it updates the value and the expiration time.
-// If the cache is full, it finds the least loaded shard to store the new entry.
-func (m *CacheManager) SetTTL(key string, val interface{}, size uint64, ttl time.Duration) {
-	if m.enableDynamicShardScaling {
-		m.dynamicShardScaling()
+// GetScan retrieves the value associated with the given key without promoting
+// it in the LRU. It is meant for bulk scans, so that reading many cold entries
+// once does not push the hot working set out of the cache.
+// If the key exists and has not expired, it returns the value; otherwise, it returns nil.
+func (m *CacheManager) GetScan(key string) interface{} {
+	if m.closed.Load() {
+		return nil
 	}
+	shard := m.lockKey(key)
+	node, ok := shard.lookup(key, time.Now().Unix())
+	m.stats.recordRead(ok)
+	m.logOp(OpGet, key, ok)
+	if !ok {
+		m.unlockKey(shard)
+		return nil
+	}
+	val := node.Value
+	m.unlockKey(shard)
 
-	hashVal := m.jch.Hash(key)
-	shardIndex := hashVal % uint64(len(m.pool))
-	shard := m.pool[shardIndex]
+	return m.resolveValue(shard, node, val)
+}
 
-	shard.mut.Lock()
-	if shard.size >= shard.capacity {
-		shard.mut.Unlock()
-		shard = m.findLeastLoadedShard()
-		shard.mut.Lock()
+// Peek returns the value stored under key and whether it is present and
+// unexpired, without touching the entry: it is neither promoted in the LRU nor
+// marked as used, and expired entries are reported missing but left for the
+// cleaner. Peek is not counted in Stats or the access log, so monitoring and
+// debugging tools can inspect the cache without skewing eviction decisions.
+func (m *CacheManager) Peek(key string) (interface{}, bool) {
+	if m.closed.Load() {
+		return nil, false
 	}
+	shard := m.rlockKey(key)
+	node, ok := shard.pool[key]
+	if !ok || shard.stale(node, time.Now().Unix()) {
+		m.runlockKey(shard)
+		return nil, false
+	}
+	val := node.Value
+	m.runlockKey(shard)
+
+	return m.resolveValue(shard, node, val), true
+}
 
-	expiry := int64(0)
-	if ttl > 0 {
-		expiry = time.Now().Add(ttl).Unix()
+// GetAndTouch retrieves the value associated with the given key and, in the same
+// locked operation, resets its expiry to now plus ttl and promotes it in the LRU.
+// A ttl of zero or less makes the entry never expire. The boolean reports whether
+// the key was present and unexpired.
+func (m *CacheManager) GetAndTouch(key string, ttl time.Duration) (interface{}, bool) {
+	if m.closed.Load() {
+		return nil, false
 	}
+	defer m.reserve(0)
+	shard := m.lockKey(key)
+	m.applyPending(shard, key)
+	node, ok := shard.lookup(key, time.Now().Unix())
+	m.stats.recordRead(ok)
+	m.logOp(OpGet, key, ok)
+	if !ok {
+		m.unlockKey(shard)
+		return nil, false
+	}
+	node.expiredAt = expiryFor(ttl)
+	node.ttl = ttl
+	shard.moveToHead(node)
+	val := node.Value
+	m.unlockKey(shard)
 
-	if node, exists := shard.pool[key]; exists {
-		shard.shardSize -= node.nodeSize
-		node.Value = val
-		node.nodeSize = size
-		shard.shardSize += size
-		node.expiredAt = expiry
-		shard.moveToHead(node)
-		shard.mut.Unlock()
-		return
+	return m.resolveValue(shard, node, val), true
+}
+
+// Touch resets the expiry of the entry stored under key to now plus ttl and
+// promotes it in the LRU, without rewriting its value. A ttl of zero or less
+// makes the entry never expire. It returns false, changing nothing, if the key
+// is missing or has expired.
+func (m *CacheManager) Touch(key string, ttl time.Duration) bool {
+	defer m.reserve(0)
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	m.applyPending(shard, key)
+	node, ok := shard.lookup(key, time.Now().Unix())
+	if !ok {
+		return false
 	}
+	node.expiredAt = expiryFor(ttl)
+	node.ttl = ttl
+	shard.moveToHead(node)
+	return true
+}
 
-	newNode := &Nodes{
-		Key:       key,
-		Value:     val,
-		expiredAt: expiry,
-		nodeSize:  size,
+// Expire sets the expiry of the live entry stored under key to now plus ttl,
+// leaving its value and its position in the LRU as they are. A ttl of zero or
+// less makes the entry never expire, like Persist. It returns whether the key
+// was found.
+func (m *CacheManager) Expire(key string, ttl time.Duration) bool {
+	return m.setExpiry(key, expiryFor(ttl), ttl)
+}
+
+// Persist clears the expiry of the live entry stored under key, so that it
+// never expires and the cleaner no longer removes it; it can still be evicted.
+// It returns whether the key was found.
+func (m *CacheManager) Persist(key string) bool {
+	return m.setExpiry(key, 0, 0)
+}
+
+// setExpiry implements Expire and Persist.
+func (m *CacheManager) setExpiry(key string, expiry int64, ttl time.Duration) bool {
+	defer m.reserve(0)
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	m.applyPending(shard, key)
+	node, ok := shard.lookup(key, time.Now().Unix())
+	if !ok {
+		return false
 	}
-	shard.shardSize += size
-	shard.addToHead(newNode)
-	shard.pool[key] = newNode
-	shard.size++
+	node.expiredAt = expiry
+	node.ttl = ttl
+	return true
+}
+
+// TouchMulti resets the expiry of every live entry among keys to now plus ttl and
+// promotes it in the LRU. A ttl of zero or less makes the entries never expire.
+// Keys are grouped by shard so each shard is locked once. It returns the number
+// of entries that were present and refreshed.
+func (m *CacheManager) TouchMulti(keys []string, ttl time.Duration) int {
+	expiry := expiryFor(ttl)
+	now := time.Now().Unix()
+	touched := 0
 
-	if shard.size > shard.capacity {
-		evicted := shard.removeTail()
-		if evicted != nil {
-			delete(shard.pool, evicted.Key)
-			shard.size--
+	defer m.reserve(0)
+	m.poolMut.RLock()
+	var pending []removal
+	for shard, group := range m.groupByShard(keys) {
+		shard.lock()
+		for _, key := range group {
+			m.applyPending(shard, key)
+			if node, ok := shard.lookup(key, now); ok {
+				node.expiredAt = expiry
+				node.ttl = ttl
+				shard.moveToHead(node)
+				touched++
+			}
 		}
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+	}
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+	return touched
+}
+
+// NoExpiry is the remaining lifetime reported by TTL for entries that never expire.
+const NoExpiry time.Duration = -1
+
+// TTL returns the remaining lifetime of the entry stored under key, without
+// returning its value or promoting it in the LRU. Entries that never expire
+// report NoExpiry. The boolean is false if the key is missing or has expired.
+func (m *CacheManager) TTL(key string) (time.Duration, bool) {
+	shard := m.rlockKey(key)
+	defer m.runlockKey(shard)
+
+	node, exists := shard.pool[key]
+	if !exists {
+		return 0, false
 	}
 
-	shard.mut.Unlock()
+	now := time.Now()
+	if shard.stale(node, now.Unix()) {
+		return 0, false
+	}
+	if node.expiredAt == 0 {
+		return NoExpiry, true
+	}
+	return time.Unix(node.expiredAt, 0).Sub(now), true
 }
 
-// Get retrieves the value associated with the given key from the cache.
-// If the key exists and has not expired, it returns the value; otherwise, it returns nil.
-func (m *CacheManager) Get(key string) interface{} {
-	hashVal := m.jch.Hash(key)
-	shardIndex := hashVal % uint64(len(m.pool))
-	shard := m.pool[shardIndex]
+// GetTTL is TTL under the name callers of other caches may look for: it
+// returns the remaining lifetime of the entry stored under key, NoExpiry for
+// entries that never expire, and false for missing or expired entries. An
+// expired entry is left in place and removed lazily, by the cleaner or the
+// next read of its key.
+func (m *CacheManager) GetTTL(key string) (time.Duration, bool) {
+	return m.TTL(key)
+}
+
+// Rename atomically moves the entry stored under oldKey, including its value,
+// size and expiry, to newKey, overwriting any entry already stored there.
+// It returns whether oldKey existed, and ErrReadOnly, leaving both entries in
+// place, if newKey holds a read-only entry stored by SetReadOnly. When the
+// keys live in different shards, both shards are locked in index order to
+// avoid deadlocks. A write of oldKey still buffered by WriteCoalesceWindow is
+// applied before the move, and one of newKey is discarded by it.
+func (m *CacheManager) Rename(oldKey, newKey string) (bool, error) {
+	defer m.reserve(0)
+	m.poolMut.RLock()
+	oldIndex := m.shardIndex(oldKey)
+	newIndex := m.shardIndex(newKey)
+	src, dst := m.pool[oldIndex], m.pool[newIndex]
+
+	if oldIndex == newIndex {
+		src.lock()
+		defer m.unlockKey(src)
+		return m.rename(src, src, oldKey, newKey)
+	}
+
+	first, second := src, dst
+	if newIndex < oldIndex {
+		first, second = dst, src
+	}
+	first.lock()
+	second.lock()
+	found, err := m.rename(src, dst, oldKey, newKey)
+	pending := append(first.takePending(), second.takePending()...)
+	second.unlock()
+	first.unlock()
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+	return found, err
+}
+
+// rename moves the entry stored under oldKey in src to newKey in dst.
+// The caller must hold the locks of both shards.
+func (m *CacheManager) rename(src, dst *NodeShards, oldKey, newKey string) (bool, error) {
+	m.applyPending(src, oldKey)
+	now := time.Now().Unix()
+	node, ok := src.lookup(oldKey, now)
+	if !ok {
+		return false, nil
+	}
+	if oldKey == newKey {
+		return true, nil
+	}
+
+	existing, exists := dst.pool[newKey]
+	if exists && existing.readOnly && !dst.stale(existing, now) {
+		return true, ErrReadOnly
+	}
+
+	m.dropPending(newKey)
+	src.unlink(node)
+	if exists {
+		dst.deleteNode(existing, removalRemoved)
+	}
+	src.handOver(dst, node.nodeSize)
+
+	node.Key = newKey
+	dst.insert(node)
+	dst.evictOverCapacity()
+	dst.evictOverCost()
+	return true, nil
+}
+
+// Remove deletes the key-value pair associated with the given key from the cache.
+// It also removes the node from the eviction policy if it exists, and gives its
+// size back to the shard's and the cache's cost accounting.
+func (m *CacheManager) Remove(key string) {
+	m.dropPending(key)
 
-	shard.mut.Lock()
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
 
 	node, exists := shard.pool[key]
+	m.logOp(OpRemove, key, exists)
 	if exists {
+		shard.deleteNode(node, removalRemoved)
+	}
+}
+
+// ShardCapacity returns the maximum number of nodes the shard at index i can hold.
+// It returns 0 if i is out of range.
+func (m *CacheManager) ShardCapacity(i int) int {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	if i < 0 || i >= len(m.pool) {
+		return 0
+	}
+
+	shard := m.pool[i]
+	shard.mut.RLock()
+	defer shard.mut.RUnlock()
+	return shard.capacity
+}
+
+// SetShardCapacity changes the maximum number of nodes the shard at index i can hold.
+// If the new capacity is smaller than the current number of nodes, the least recently
+// used nodes are evicted until the shard fits.
+func (m *CacheManager) SetShardCapacity(i int, capacity int) error {
+	if capacity <= 0 {
+		return ErrInvalidCapacity
+	}
+
+	m.poolMut.RLock()
+	if i < 0 || i >= len(m.pool) {
+		m.poolMut.RUnlock()
+		return ErrShardIndex
+	}
+
+	shard := m.pool[i]
+	shard.lock()
+	shard.capacity = capacity
+	for shard.size > shard.capacity {
+		if shard.evict() == nil {
+			break
+		}
+	}
+	pending := shard.takePending()
+	shard.unlock()
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+	m.signalRelease()
+	return nil
+}
+
+// Cost returns the total cost, as passed to Set and SetTTL, of all nodes
+// currently held by the cache. It is the figure checked against MaxCost.
+func (m *CacheManager) Cost() uint64 {
+	return m.cost.Load()
+}
+
+// AgeStats returns the creation times of the oldest and newest live entries
+// in the cache. Both are the zero time if the cache holds no live entries.
+// It scans every shard, so it is intended for monitoring rather than hot paths.
+func (m *CacheManager) AgeStats() (oldest, newest time.Time) {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	now := time.Now().Unix()
+	var minCreated, maxCreated int64
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		for _, node := range shard.pool {
+			if shard.stale(node, now) {
+				continue
+			}
+			if minCreated == 0 || node.createdAt < minCreated {
+				minCreated = node.createdAt
+			}
+			if node.createdAt > maxCreated {
+				maxCreated = node.createdAt
+			}
+		}
+		shard.mut.RUnlock()
+	}
+
+	if maxCreated == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return time.Unix(minCreated, 0), time.Unix(maxCreated, 0)
+}
+
+// Drain stops the cache from accepting writes. While draining, Set, SetTTL and
+// SetLazy are no-ops, but Get keeps serving the entries already stored. This is
+// useful during graceful shutdown or failover. Call Undrain to resume writes.
+func (m *CacheManager) Drain() {
+	m.draining.Store(true)
+}
+
+// Undrain resumes accepting writes after a call to Drain. It has no effect
+// once the cache is closed.
+func (m *CacheManager) Undrain() {
+	if m.closed.Load() {
+		return
+	}
+	m.draining.Store(false)
+}
+
+// ShardBytes returns the total cost of the entries held by each shard, indexed
+// like the shard pool. It helps spot shards that hold few but large entries.
+func (m *CacheManager) ShardBytes() []uint64 {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	bytes := make([]uint64, len(m.pool))
+	for i, shard := range m.pool {
+		shard.mut.RLock()
+		bytes[i] = shard.shardSize
+		shard.mut.RUnlock()
+	}
+	return bytes
+}
+
+// ShardSizes returns the number of entries held by each shard, indexed like
+// the shard pool. Unlike Len, it counts expired entries that have not been
+// removed yet, reporting what each shard actually holds; it is meant for
+// debugging shard balance.
+func (m *CacheManager) ShardSizes() []int {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	sizes := make([]int, len(m.pool))
+	for i, shard := range m.pool {
+		shard.mut.RLock()
+		sizes[i] = shard.size
+		shard.mut.RUnlock()
+	}
+	return sizes
+}
+
+// Len returns the number of live entries held by the cache. Entries that have
+// expired, or were invalidated by BumpEpoch, but have not been removed yet are
+// not counted, so Len visits every entry and is linear in the size of the cache.
+func (m *CacheManager) Len() int {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	count := 0
+	now := time.Now().Unix()
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		for _, node := range shard.pool {
+			if !shard.stale(node, now) {
+				count++
+			}
+		}
+		shard.mut.RUnlock()
+	}
+	return count
+}
+
+// SizeHistogram counts live entries by size. buckets holds ascending upper
+// bounds: the count at index i is the number of entries whose size is at most
+// buckets[i] and greater than buckets[i-1]. The returned slice has one extra
+// element at the end counting entries larger than the last bound.
+func (m *CacheManager) SizeHistogram(buckets []uint64) []int {
+	counts := make([]int, len(buckets)+1)
+
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	now := time.Now().Unix()
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		for _, node := range shard.pool {
+			if shard.stale(node, now) {
+				continue
+			}
+			i := sort.Search(len(buckets), func(i int) bool {
+				return node.nodeSize <= buckets[i]
+			})
+			counts[i]++
+		}
+		shard.mut.RUnlock()
+	}
+	return counts
+}
+
+// LoadFactor returns the number of entries held by the cache divided by the
+// total node capacity of its shards, in the range 0 to 1.
+func (m *CacheManager) LoadFactor() float64 {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	var size, capacity int
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		size += shard.size
+		capacity += shard.capacity
+		shard.mut.RUnlock()
+	}
+
+	if capacity == 0 {
+		return 0
+	}
+	return float64(size) / float64(capacity)
+}
+
+// RemovePrefix deletes every entry whose key starts with prefix and returns the
+// number of entries removed. Each shard is locked once, and heap-ordered
+// eviction policies rebuild their heap a single time rather than once per
+// removed key.
+func (m *CacheManager) RemovePrefix(prefix string) int {
+	return m.removeMatching(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// removeMatching deletes every entry whose key satisfies match and returns
+// the number of entries removed, locking each shard once and removing the
+// matched nodes with removeBulk.
+func (m *CacheManager) removeMatching(match func(key string) bool) int {
+	var pending []removal
+	m.poolMut.RLock()
+	removed := 0
+	for _, shard := range m.pool {
+		shard.lock()
+		var matched []*Nodes
+		for key, node := range shard.pool {
+			if match(key) {
+				matched = append(matched, node)
+			}
+		}
+		shard.removeBulk(matched)
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+		removed += len(matched)
+	}
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+	return removed
+}
+
+// RangeLRU calls fn for every live entry, walking each shard from its most to
+// its least recently used entry. Ordering is per shard: shards are visited in
+// pool order and no global recency order is merged across them. Each shard's
+// entries are collected under its lock and fn is called after it is released,
+// so fn may use the cache. Iteration stops when fn returns false.
+func (m *CacheManager) RangeLRU(fn func(key string, value interface{}) bool) {
+	m.poolMut.RLock()
+	shards := append([]*NodeShards(nil), m.pool...)
+	m.poolMut.RUnlock()
+
+	for _, shard := range shards {
 		now := time.Now().Unix()
-		if node.expiredAt > 0 && node.expiredAt <= now {
-			shard.shardSize -= node.nodeSize
-			shard.removeNode(node)
-			delete(shard.pool, key)
-			shard.size--
-			shard.mut.Unlock()
-			return nil
-		}
-		shard.moveToHead(node)
-		shard.mut.Unlock()
-		return node.Value
-	}
-	shard.mut.Unlock()
+
+		m.poolMut.RLock()
+		shard.mut.RLock()
+		nodes := make([]*Nodes, 0, shard.size)
+		keys := make([]string, 0, shard.size)
+		values := make([]interface{}, 0, shard.size)
+		for node := shard.head.next; node != shard.tail && node != nil; node = node.next {
+			if shard.stale(node, now) {
+				continue
+			}
+			nodes = append(nodes, node)
+			keys = append(keys, node.Key)
+			values = append(values, node.Value)
+		}
+		m.runlockKey(shard)
+
+		for i, node := range nodes {
+			if !fn(keys[i], m.resolveValue(shard, node, values[i])) {
+				return
+			}
+		}
+	}
+}
+
+// BumpEpoch invalidates every entry currently in the cache in constant time.
+// Entries written before the bump are treated as missing by all reads and are
+// dropped lazily, on access or by the cleaner; entries written afterwards are
+// served normally.
+func (m *CacheManager) BumpEpoch() {
+	m.epoch.Add(1)
+}
+
+// EnsureShards grows the pool to at least n shards at once, capped at the
+// configured ShardCap, and rebalances existing entries across them. It is meant
+// to be called before a predictable burst of writes, so that the pool does not
+// have to grow one shard at a time while the burst is in progress. It returns
+// the number of shards in the pool afterwards. The pool is never shrunk, and
+// dynamic sharding no longer shrinks it below n either.
+func (m *CacheManager) EnsureShards(n int) int {
+	if n > m.shardCap {
+		n = m.shardCap
+	}
+
+	m.poolMut.Lock()
+	m.minPool = max(m.minPool, n)
+	added := 0
+	for len(m.pool) < n {
+		m.addShard()
+		added++
+	}
+	var pending []removal
+	if added > 0 {
+		pending = m.rebalanceNodes()
+	}
+	total := len(m.pool)
+	m.poolMut.Unlock()
+	m.dispose(pending)
+	if added > 0 {
+		m.signalRelease()
+	}
+
+	if added > 0 && m.onShardChange != nil {
+		m.onShardChange(added, total)
+	}
+	return total
+}
+
+// Prewarm grows the pool to ShardCap shards at once. See EnsureShards.
+func (m *CacheManager) Prewarm() {
+	m.EnsureShards(m.shardCap)
+}
+
+// ClearShard removes every entry from the shard at index i, leaving the other
+// shards intact. The removed entries are treated as evicted: OnEvict is called
+// for each of them, followed by the other release hooks such as CloseOnEvict,
+// and they are counted in Stats.Evictions and EvictionRate. It returns
+// ErrShardIndex if i is out of range.
+func (m *CacheManager) ClearShard(i int) error {
+	m.poolMut.RLock()
+	if i < 0 || i >= len(m.pool) {
+		m.poolMut.RUnlock()
+		return ErrShardIndex
+	}
+
+	shard := m.pool[i]
+	shard.lock()
+	removed := shard.clear(removalEvicted)
+	pending := shard.takePending()
+	shard.unlock()
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+
+	if removed > 0 {
+		m.stats.recordEvictions(uint64(removed))
+		m.signalRelease()
+	}
 	return nil
 }
 
-// Remove deletes the key-value pair associated with the given key from the cache.
-// It also removes the node from the eviction heap if it exists.
-func (m *CacheManager) Remove(key string) {
-	hashVal := m.jch.GetBucket(key)
-	shardIndex := hashVal % uint64(len(m.pool))
-	shard := m.pool[shardIndex]
+// Clear removes every entry from the cache, shard by shard under each shard's
+// lock, and discards writes buffered by WriteCoalesceWindow. The removed entries
+// go through the same release hooks as entries removed with Remove. Background
+// goroutines such as the cleaners keep running, and the cache stays usable.
+func (m *CacheManager) Clear() {
+	if c := m.coalescer; c != nil {
+		c.mut.Lock()
+		c.pending = make(map[string]pendingWrite)
+		c.mut.Unlock()
+	}
+
+	var pending []removal
+	m.poolMut.RLock()
+	removed := 0
+	for _, shard := range m.pool {
+		shard.lock()
+		removed += shard.clear(removalRemoved)
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+	}
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+	if removed > 0 {
+		m.signalRelease()
+	}
+}
+
+// Close stops every background goroutine of the cache, such as the shard
+// cleaners and the stats report, and blocks until they have exited. Writes
+// buffered by WriteCoalesceWindow are applied first. After Close, writes are
+// no-ops and reads report every key as missing. Close may be called more than
+// once; later calls return immediately.
+func (m *CacheManager) Close() {
+	m.closeOnce.Do(func() {
+		m.spawnMut.Lock()
+		close(m.done)
+		m.spawnMut.Unlock()
+
+		m.poolMut.RLock()
+		for _, shard := range m.pool {
+			close(shard.cleanerStop)
+		}
+		m.poolMut.RUnlock()
+
+		m.wg.Wait()
+		m.draining.Store(true)
+		m.closed.Store(true)
+	})
+}
+
+// Rehash replaces the hash function used to place keys in shards and moves
+// every entry to the shard it belongs to under newHasher, returning the number
+// of entries that changed shard. As with the internal hash, a key's hash tag is
+// hashed rather than the whole key. All shards are locked while entries move,
+// so no entry is lost or served from the wrong shard; entries are otherwise
+// kept as they are, except that stale ones are dropped. A configured ShardFunc
+// still takes precedence over the hasher.
+func (m *CacheManager) Rehash(newHasher func(string) uint64) int {
+	m.poolMut.Lock()
+
+	for _, shard := range m.pool {
+		shard.lock()
+	}
 
-	shard.mut.Lock()
-	defer shard.mut.Unlock()
+	m.hasher.Store(&newHasher)
+	moved := 0
+	for i, shard := range m.pool {
+		for key := range shard.pool {
+			if m.shardIndex(key) != i {
+				moved++
+			}
+		}
+	}
+	m.redistribute(m.pool)
+
+	pending := m.unlockAll()
+	m.poolMut.Unlock()
+	m.dispose(pending)
+	return moved
+}
+
+// Ready reports whether the cache can serve requests: it holds at least one
+// shard, has not been closed and is not draining. It is meant for readiness
+// probes.
+func (m *CacheManager) Ready() bool {
+	if m.closed.Load() || m.draining.Load() {
+		return false
+	}
+
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+	return len(m.pool) > 0
+}
+
+// EvictionCandidates returns the keys of up to n entries the cache would evict
+// first, coldest first, without removing them, so that the caller can choose
+// which to Remove. Each shard proposes its own coldest entries in the order its
+// eviction policy would evict them, and the proposals are merged in the same order.
+// Pinned entries, pure TTL entries and stale entries are never proposed.
+func (m *CacheManager) EvictionCandidates(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	type candidate struct {
+		key  string
+		node Nodes
+	}
+	var proposals [][]candidate
+	for _, shard := range m.pool {
+		now := time.Now().Unix()
+		shard.mut.RLock()
+		var proposal []candidate
+		for _, node := range shard.coldest(n, now) {
+			proposal = append(proposal, candidate{key: node.Key, node: node.ranking()})
+		}
+		shard.mut.RUnlock()
+		if len(proposal) > 0 {
+			proposals = append(proposals, proposal)
+		}
+	}
+
+	// Merge the proposals rather than sorting them, so that each shard's
+	// own order is kept even where evictsBefore cannot tell its entries
+	// apart, such as LRU entries used within the same second.
+	var candidates []candidate
+	for len(candidates) < n && len(proposals) > 0 {
+		next := 0
+		for i := 1; i < len(proposals); i++ {
+			if evictsBefore(&proposals[i][0].node, &proposals[next][0].node) {
+				next = i
+			}
+		}
+		candidates = append(candidates, proposals[next][0])
+		if proposals[next] = proposals[next][1:]; len(proposals[next]) == 0 {
+			proposals = append(proposals[:next], proposals[next+1:]...)
+		}
+	}
 
-	if node, exists := shard.pool[key]; exists {
-		shard.evictionHeap.RemoveNode(node)
-		shard.removeNode(node)
-		delete(shard.pool, key)
-		shard.size--
-		heap.Init(shard.evictionHeap)
+	keys := make([]string, len(candidates))
+	for i, c := range candidates {
+		keys[i] = c.key
 	}
+	return keys
 }
@@ -0,0 +1,1091 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSetRemoveFullShards fills every shard past capacity and checks that
+// Remove still finds each key Set stored, since both resolve the same shard.
+func TestSetRemoveFullShards(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 100})
+	defer m.Close()
+
+	for i := 0; i < 10000; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if orphans := m.Orphans(); len(orphans) > 0 {
+		t.Fatalf("Orphans() = %v, want none", orphans)
+	}
+	for i := 0; i < 10000; i++ {
+		m.Remove(fmt.Sprintf("key%d", i))
+	}
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Len() = %d after removing every key, want 0", n)
+	}
+}
+
+// TestSetUpdatesLiveKeyInFullShard updates live keys while every shard is
+// full and checks that the new values replace the old ones.
+func TestSetUpdatesLiveKeyInFullShard(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+
+	for i := 0; i < 100; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	updated := 0
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := m.Peek(key); !ok {
+			continue
+		}
+		m.Set(key, "updated", 1)
+		if v, ok := m.GetOK(key); !ok || v != "updated" {
+			t.Fatalf("GetOK(%q) = %v, %v; want updated, true", key, v, ok)
+		}
+		updated++
+	}
+	if updated == 0 {
+		t.Fatal("no key survived to be updated")
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestClearShard fills several shards, clears one, and checks that only its
+// entries are gone, each reported to OnEvict and counted as an eviction, and
+// that the other shards are left intact.
+func TestClearShard(t *testing.T) {
+	evicted := make(map[string]bool)
+	m := New(&Config{
+		ShardCap: 4,
+		NodeCap:  100,
+		OnEvict: func(key string, value interface{}) {
+			evicted[key] = true
+		},
+	})
+	defer m.Close()
+
+	for i := 0; i < 100; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	m.poolMut.RLock()
+	var cleared []string
+	for key := range m.pool[1].pool {
+		cleared = append(cleared, key)
+	}
+	m.poolMut.RUnlock()
+	if len(cleared) == 0 {
+		t.Fatal("shard 1 holds no entries to clear")
+	}
+
+	if err := m.ClearShard(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ClearShard(4); err != ErrShardIndex {
+		t.Fatalf("ClearShard(4) = %v, want ErrShardIndex", err)
+	}
+
+	if len(evicted) != len(cleared) {
+		t.Fatalf("OnEvict called for %d entries, want %d", len(evicted), len(cleared))
+	}
+	for _, key := range cleared {
+		if !evicted[key] {
+			t.Fatalf("OnEvict not called for %q", key)
+		}
+		if _, ok := m.Peek(key); ok {
+			t.Fatalf("%q survived ClearShard", key)
+		}
+	}
+	if n := m.Len(); n != 100-len(cleared) {
+		t.Fatalf("Len() = %d, want %d", n, 100-len(cleared))
+	}
+	if e := m.Stats().Evictions; e != uint64(len(cleared)) {
+		t.Fatalf("Stats().Evictions = %d, want the %d entries cleared", e, len(cleared))
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTouch checks that Touch extends and promotes a live entry, and leaves
+// missing and expired keys alone.
+func TestTouch(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		m := New(&Config{ShardCap: 1, NodeCap: 10})
+		defer m.Close()
+		if m.Touch("missing", time.Hour) {
+			t.Fatal("Touch(missing) = true, want false")
+		}
+		if _, ok := m.Peek("missing"); ok {
+			t.Fatal("Touch created the missing key")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		m := New(&Config{ShardCap: 1, NodeCap: 10})
+		defer m.Close()
+		m.SetTTL("key", 1, 1, time.Hour)
+		shard := m.lockKey("key")
+		shard.pool["key"].expiredAt = time.Now().Add(-time.Minute).Unix()
+		m.unlockKey(shard)
+
+		if m.Touch("key", time.Hour) {
+			t.Fatal("Touch(expired) = true, want false")
+		}
+		if _, ok := m.GetOK("key"); ok {
+			t.Fatal("Touch revived the expired key")
+		}
+	})
+
+	t.Run("live", func(t *testing.T) {
+		m := New(&Config{ShardCap: 1, NodeCap: 2})
+		defer m.Close()
+		m.SetTTL("old", 1, 1, time.Minute)
+		m.SetTTL("new", 2, 1, time.Minute)
+
+		if !m.Touch("old", time.Hour) {
+			t.Fatal("Touch(old) = false, want true")
+		}
+		if ttl, ok := m.TTL("old"); !ok || ttl < 59*time.Minute {
+			t.Fatalf("TTL(old) = %v, %v after Touch; want about an hour", ttl, ok)
+		}
+		if v, ok := m.Peek("old"); !ok || v != 1 {
+			t.Fatalf("Peek(old) = %v, %v after Touch; want 1, true", v, ok)
+		}
+
+		// Touch promoted old, so the next write evicts new instead.
+		m.SetTTL("third", 3, 1, time.Minute)
+		if _, ok := m.Peek("old"); !ok {
+			t.Fatal("old was evicted after Touch promoted it")
+		}
+		if _, ok := m.Peek("new"); ok {
+			t.Fatal("new survived, want it evicted as least recently used")
+		}
+	})
+}
+
+// TestGetAndTouch checks that GetAndTouch returns the value of a live entry
+// while extending its lifetime, and reports a missing key.
+func TestGetAndTouch(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.SetTTL("key", 1, 1, time.Minute)
+
+	if v, ok := m.GetAndTouch("key", time.Hour); !ok || v != 1 {
+		t.Fatalf("GetAndTouch(key) = %v, %v; want 1, true", v, ok)
+	}
+	if ttl, ok := m.TTL("key"); !ok || ttl < 59*time.Minute {
+		t.Fatalf("TTL(key) = %v, %v after GetAndTouch; want about an hour", ttl, ok)
+	}
+	if v, ok := m.GetAndTouch("missing", time.Hour); ok || v != nil {
+		t.Fatalf("GetAndTouch(missing) = %v, %v; want nil, false", v, ok)
+	}
+	if s := m.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Fatalf("Stats() = %d hits, %d misses; want 1 and 1", s.Hits, s.Misses)
+	}
+}
+
+// withNode runs fn on the node stored under key with its shard locked, so
+// that a test can age or expire an entry without waiting.
+func withNode(t *testing.T, m *CacheManager, key string, fn func(node *Nodes)) {
+	t.Helper()
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+	node, ok := shard.pool[key]
+	if !ok {
+		t.Fatalf("no node stored under %q", key)
+	}
+	fn(node)
+}
+
+// TestAgeStats checks that AgeStats reports the creation times of the oldest
+// and newest live entries, and zero times for an empty cache.
+func TestAgeStats(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	if oldest, newest := m.AgeStats(); !oldest.IsZero() || !newest.IsZero() {
+		t.Fatalf("AgeStats() = %v, %v on an empty cache; want zero times", oldest, newest)
+	}
+
+	now := time.Now().Unix()
+	m.Set("old", 1, 1)
+	m.Set("new", 2, 1)
+	m.Set("expired", 3, 1)
+	withNode(t, m, "old", func(node *Nodes) { node.createdAt = now - 100 })
+	withNode(t, m, "expired", func(node *Nodes) {
+		node.createdAt = now - 200
+		node.expiredAt = now - 1
+	})
+
+	oldest, newest := m.AgeStats()
+	if oldest.Unix() != now-100 {
+		t.Errorf("oldest = %v, want the creation of old", oldest)
+	}
+	if newest.Unix() < now {
+		t.Errorf("newest = %v, want the creation of new", newest)
+	}
+}
+
+// TestDrain checks that a draining cache ignores writes but keeps serving
+// the entries it holds, until Undrain.
+func TestDrain(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.Set("key", 1, 1)
+
+	m.Drain()
+	m.Set("key", 2, 1)
+	m.SetTTL("other", 3, 1, time.Hour)
+	m.SetLazy("lazy", func() (interface{}, uint64) { return 4, 1 }, time.Hour)
+	if v := m.Get("key"); v != 1 {
+		t.Fatalf("Get(key) = %v while draining, want 1", v)
+	}
+	if n := m.Len(); n != 1 {
+		t.Fatalf("Len() = %d while draining, want 1", n)
+	}
+
+	m.Undrain()
+	m.Set("key", 2, 1)
+	if v := m.Get("key"); v != 2 {
+		t.Fatalf("Get(key) = %v after Undrain, want 2", v)
+	}
+}
+
+// TestTTL checks the lifetimes TTL reports, and that it does not promote the
+// entry it reads.
+func TestTTL(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 3})
+	defer m.Close()
+	m.SetTTL("hour", 1, 1, time.Hour)
+	m.SetTTL("forever", 2, 1, 0)
+	m.SetTTL("expired", 3, 1, time.Hour)
+	withNode(t, m, "expired", func(node *Nodes) { node.expiredAt = time.Now().Unix() - 1 })
+
+	if ttl, ok := m.TTL("hour"); !ok || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL(hour) = %v, %v; want about an hour", ttl, ok)
+	}
+	if ttl, ok := m.TTL("forever"); !ok || ttl != NoExpiry {
+		t.Errorf("TTL(forever) = %v, %v; want NoExpiry, true", ttl, ok)
+	}
+	for _, key := range []string{"expired", "missing"} {
+		if ttl, ok := m.TTL(key); ok || ttl != 0 {
+			t.Errorf("TTL(%s) = %v, %v; want 0, false", key, ttl, ok)
+		}
+	}
+
+	// hour is the least recently used entry; reading its TTL keeps it so.
+	m.Remove("expired")
+	m.SetTTL("new", 4, 1, time.Hour)
+	m.SetTTL("newer", 5, 1, time.Hour)
+	if _, ok := m.Peek("hour"); ok {
+		t.Error("hour survived, want TTL to leave it least recently used")
+	}
+}
+
+// TestRename moves entries within a shard and across shards and checks that
+// they keep their value, size and expiry and replace the destination.
+func TestRename(t *testing.T) {
+	// Keys starting with "a" live in shard 0, the others in shard 1.
+	m := New(&Config{ShardCap: 2, NodeCap: 10, ShardFunc: func(key string, n int) int {
+		if key[0] == 'a' {
+			return 0
+		}
+		return 1
+	}})
+	defer m.Close()
+
+	for _, tc := range []struct{ from, to string }{{"a1", "a2"}, {"a2", "b1"}} {
+		m.SetTTL(tc.to, "old", 1, 0)
+		m.SetTTL(tc.from, "value", 7, time.Hour)
+
+		found, err := m.Rename(tc.from, tc.to)
+		if !found || err != nil {
+			t.Fatalf("Rename(%s, %s) = %v, %v; want true, nil", tc.from, tc.to, found, err)
+		}
+		if _, ok := m.Peek(tc.from); ok {
+			t.Errorf("%s still present after Rename", tc.from)
+		}
+		if v, ok := m.Peek(tc.to); !ok || v != "value" {
+			t.Errorf("Peek(%s) = %v, %v; want value, true", tc.to, v, ok)
+		}
+		if ttl, ok := m.TTL(tc.to); !ok || ttl <= 59*time.Minute {
+			t.Errorf("TTL(%s) = %v, %v; want the hour of %s", tc.to, ttl, ok, tc.from)
+		}
+		if c := m.Cost(); c != 7 {
+			t.Errorf("Cost() = %d after Rename(%s, %s), want 7", c, tc.from, tc.to)
+		}
+		m.Remove(tc.to)
+	}
+
+	if found, err := m.Rename("missing", "b2"); found || err != nil {
+		t.Fatalf("Rename(missing) = %v, %v; want false, nil", found, err)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGetScanDoesNotPromote checks that GetScan reads an entry, counting the
+// hit, while leaving it as the next one to evict.
+func TestGetScanDoesNotPromote(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 2})
+	defer m.Close()
+	m.Set("cold", 1, 1)
+	m.Set("warm", 2, 1)
+
+	if v := m.GetScan("cold"); v != 1 {
+		t.Fatalf("GetScan(cold) = %v, want 1", v)
+	}
+	if v := m.GetScan("missing"); v != nil {
+		t.Fatalf("GetScan(missing) = %v, want nil", v)
+	}
+	if s := m.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Fatalf("Stats() = %d hits, %d misses; want 1 and 1", s.Hits, s.Misses)
+	}
+
+	m.Set("new", 3, 1)
+	if _, ok := m.Peek("cold"); ok {
+		t.Fatal("cold survived, want GetScan to leave it least recently used")
+	}
+	if _, ok := m.Peek("warm"); !ok {
+		t.Fatal("warm was evicted instead of cold")
+	}
+}
+
+// TestMergeLosesNoUpdates merges increments of one counter from many
+// goroutines and checks that none is lost and that the entry keeps its
+// expiry.
+func TestMergeLosesNoUpdates(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	add := func(existing, incoming interface{}) interface{} {
+		return existing.(int) + incoming.(int)
+	}
+
+	m.Merge("fresh", 5, 1, add)
+	if v := m.Get("fresh"); v != 5 {
+		t.Fatalf("Get(fresh) = %v after merging into a missing key, want 5", v)
+	}
+
+	m.SetTTL("counter", 0, 1, time.Hour)
+	var wg sync.WaitGroup
+	for g := 0; g < 50; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				m.Merge("counter", 1, 1, add)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := m.Get("counter"); v != 5000 {
+		t.Fatalf("Get(counter) = %v, want 5000", v)
+	}
+	if ttl, ok := m.TTL("counter"); !ok || ttl <= 59*time.Minute {
+		t.Fatalf("TTL(counter) = %v, %v; want the hour it was set with", ttl, ok)
+	}
+}
+
+// TestGetOrDefault checks that GetOrDefault falls back to its default only
+// for missing keys, not for entries holding nil.
+func TestGetOrDefault(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.Set("key", 1, 1)
+	m.Set("nil", nil, 1)
+
+	if v := m.GetOrDefault("key", 2); v != 1 {
+		t.Errorf("GetOrDefault(key) = %v, want 1", v)
+	}
+	if v := m.GetOrDefault("nil", 2); v != nil {
+		t.Errorf("GetOrDefault(nil) = %v, want the stored nil", v)
+	}
+	if v := m.GetOrDefault("missing", 2); v != 2 {
+		t.Errorf("GetOrDefault(missing) = %v, want the default 2", v)
+	}
+}
+
+// TestTouchMulti refreshes live, expired and missing keys spread over
+// several shards and checks that only the live ones are extended and counted.
+func TestTouchMulti(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 6; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, time.Minute)
+	}
+	withNode(t, m, "key5", func(node *Nodes) { node.expiredAt = time.Now().Unix() - 1 })
+
+	keys := []string{"key0", "key1", "key2", "key3", "key4", "key5", "missing"}
+	if n := m.TouchMulti(keys, time.Hour); n != 5 {
+		t.Fatalf("TouchMulti = %d, want the 5 live keys", n)
+	}
+	for i := 0; i < 5; i++ {
+		if ttl, ok := m.TTL(fmt.Sprintf("key%d", i)); !ok || ttl <= 59*time.Minute {
+			t.Errorf("TTL(key%d) = %v, %v; want about an hour", i, ttl, ok)
+		}
+	}
+	if _, ok := m.TTL("key5"); ok {
+		t.Error("TouchMulti revived the expired key5")
+	}
+}
+
+// TestNewCopiesConfig changes a Config, including the elements of its
+// TTLRules, after New, and checks that the cache keeps the settings it was
+// built with.
+func TestNewCopiesConfig(t *testing.T) {
+	cfg := &Config{
+		ShardCap: 1,
+		NodeCap:  2,
+		TTLRules: []TTLRule{{Prefix: "s:", TTL: time.Minute}},
+	}
+	m := New(cfg)
+	defer m.Close()
+	cfg.NodeCap = 100
+	cfg.MaxCost = 1
+	cfg.TTLRules[0].TTL = time.Hour
+
+	m.Set("s:key", 1, 10)
+	if ttl, ok := m.TTL("s:key"); !ok || ttl > time.Minute {
+		t.Errorf("TTL(s:key) = %v, %v; want the minute of the original rule", ttl, ok)
+	}
+	for i := 0; i < 5; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 10)
+	}
+	if n := m.Len(); n != 2 {
+		t.Errorf("Len() = %d, want the original NodeCap of 2", n)
+	}
+}
+
+// TestRangeLRU checks that RangeLRU walks a shard from its most to its least
+// recently used entry, and stops when fn returns false.
+func TestRangeLRU(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Set(key, key, 1)
+	}
+	m.Get("b")
+
+	var keys []string
+	m.RangeLRU(func(key string, value interface{}) bool {
+		if value != key {
+			t.Errorf("RangeLRU passed %v for %s", value, key)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if got := fmt.Sprint(keys); got != "[b d c a]" {
+		t.Fatalf("RangeLRU order = %s, want [b d c a]", got)
+	}
+
+	calls := 0
+	m.RangeLRU(func(string, interface{}) bool {
+		calls++
+		return calls < 2
+	})
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want RangeLRU to stop after 2", calls)
+	}
+}
+
+// TestGetMultiOrdered checks that GetMultiOrdered returns values and found
+// flags in the order of its keys, duplicates and stored nils included.
+func TestGetMultiOrdered(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 5; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	m.Set("nil", nil, 1)
+
+	keys := []string{"key3", "missing", "key0", "nil", "key3", "key4"}
+	values, found := m.GetMultiOrdered(keys)
+	wantValues := []interface{}{3, nil, 0, nil, 3, 4}
+	wantFound := []bool{true, false, true, true, true, true}
+	for i := range keys {
+		if values[i] != wantValues[i] || found[i] != wantFound[i] {
+			t.Errorf("position %d (%s) = %v, %v; want %v, %v", i, keys[i], values[i], found[i], wantValues[i], wantFound[i])
+		}
+	}
+	if s := m.Stats(); s.Hits != 5 || s.Misses != 1 {
+		t.Errorf("Stats() = %d hits, %d misses; want 5 and 1", s.Hits, s.Misses)
+	}
+}
+
+// TestBumpEpoch checks that BumpEpoch invalidates every entry written before
+// it, which the cleaner then reclaims, while later writes are served.
+func TestBumpEpoch(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 5; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+
+	m.BumpEpoch()
+	m.Set("after", 1, 1)
+	for i := 0; i < 5; i++ {
+		if _, ok := m.GetOK(fmt.Sprintf("key%d", i)); ok {
+			t.Errorf("key%d served after BumpEpoch", i)
+		}
+	}
+	if v := m.Get("after"); v != 1 {
+		t.Fatalf("Get(after) = %v, want 1", v)
+	}
+
+	for _, shard := range m.pool {
+		shard.cleanExpired()
+	}
+	if n := m.Len(); n != 1 {
+		t.Fatalf("Len() = %d after the cleaner ran, want only the entry written after the bump", n)
+	}
+}
+
+// TestGetSet checks that GetSet returns the value it replaces, keeping the
+// entry's expiry, and that concurrent swaps hand out every value once.
+func TestGetSet(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+
+	if old, existed := m.GetSet("key", 0, 1); existed || old != nil {
+		t.Fatalf("GetSet on a missing key = %v, %v; want nil, false", old, existed)
+	}
+	m.SetTTL("key", 0, 1, time.Hour)
+
+	var wg sync.WaitGroup
+	seen := make([]int, 101)
+	var mut sync.Mutex
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			old, existed := m.GetSet("key", i, 1)
+			if !existed {
+				t.Errorf("GetSet(%d) found no previous entry", i)
+				return
+			}
+			mut.Lock()
+			seen[old.(int)]++
+			mut.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	last := m.Get("key").(int)
+	seen[last]++
+	for v, n := range seen {
+		if n != 1 {
+			t.Errorf("value %d handed out %d times, want once", v, n)
+		}
+	}
+	if ttl, ok := m.TTL("key"); !ok || ttl <= 59*time.Minute {
+		t.Errorf("TTL(key) = %v, %v; want the hour it was set with", ttl, ok)
+	}
+}
+
+// TestClose checks that Close stops the cleaner of every shard, that the
+// closed cache ignores writes and misses every read, and that a second Close
+// returns at once.
+func TestClose(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10, EnableCleaner: true})
+	m.Set("key", 1, 1)
+	if n := m.goroutines.Load(); n < 4 {
+		t.Fatalf("%d goroutines running, want a cleaner per shard", n)
+	}
+
+	m.Close()
+	if n := m.goroutines.Load(); n != 0 {
+		t.Fatalf("%d goroutines running after Close, want 0", n)
+	}
+	if m.Ready() {
+		t.Fatal("Ready() = true after Close")
+	}
+	m.Set("other", 2, 1)
+	if v, ok := m.GetOK("key"); ok {
+		t.Fatalf("GetOK(key) = %v, true after Close; want a miss", v)
+	}
+	if v, ok := m.GetOK("other"); ok {
+		t.Fatalf("GetOK(other) = %v, true after Close; want a miss", v)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("second Close did not return")
+	}
+}
+
+// TestRehash moves every entry to the first shard with a constant hasher and
+// checks the count of moved entries, that each entry, pure TTL ones
+// included, is still found, and that later writes follow the new hasher.
+func TestRehash(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 100})
+	defer m.Close()
+	for i := 0; i < 40; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	m.SetTTLNoLRU("pure", -1, 1, time.Hour)
+	elsewhere := 0
+	for _, shard := range m.pool[1:] {
+		elsewhere += len(shard.pool)
+	}
+	if elsewhere == 0 {
+		t.Fatal("every key landed on the first shard, want them spread")
+	}
+
+	if moved := m.Rehash(func(string) uint64 { return 0 }); moved != elsewhere {
+		t.Fatalf("Rehash() = %d, want the %d entries off the first shard", moved, elsewhere)
+	}
+	m.Set("late", 40, 1)
+	if n := len(m.pool[0].pool); n != 42 {
+		t.Fatalf("first shard holds %d entries, want all 42", n)
+	}
+	for i := 0; i < 40; i++ {
+		if v := m.Get(fmt.Sprintf("key%d", i)); v != i {
+			t.Errorf("Get(key%d) = %v after Rehash, want %d", i, v, i)
+		}
+	}
+	if v := m.Get("pure"); v != -1 {
+		t.Errorf("Get(pure) = %v after Rehash, want -1", v)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGetOK checks that GetOK tells a cached nil apart from a missing or
+// expired key, counts hits and misses, and promotes the entry it finds.
+func TestGetOK(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 2})
+	defer m.Close()
+	m.Set("nil", nil, 1)
+	m.SetTTL("expired", 1, 1, time.Hour)
+	withNode(t, m, "expired", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+
+	if v, ok := m.GetOK("nil"); !ok || v != nil {
+		t.Fatalf("GetOK(nil) = %v, %v; want nil, true", v, ok)
+	}
+	if v, ok := m.GetOK("missing"); ok || v != nil {
+		t.Fatalf("GetOK(missing) = %v, %v; want nil, false", v, ok)
+	}
+	if v, ok := m.GetOK("expired"); ok || v != nil {
+		t.Fatalf("GetOK(expired) = %v, %v; want nil, false", v, ok)
+	}
+	if s := m.Stats(); s.Hits != 1 || s.Misses != 2 {
+		t.Fatalf("Stats() = %d hits, %d misses; want 1 and 2", s.Hits, s.Misses)
+	}
+
+	m.Set("other", 2, 1)
+	m.GetOK("nil")
+	m.Set("new", 3, 1)
+	if _, ok := m.GetOK("nil"); !ok {
+		t.Fatal("GetOK did not promote nil, which was evicted")
+	}
+	if _, ok := m.GetOK("other"); ok {
+		t.Fatal("other survived, want it evicted as the least recently used")
+	}
+}
+
+// TestSetTTLNoLRU checks that a pure TTL entry survives capacity pressure,
+// is skipped by RangeLRU, stays a pure TTL entry when overwritten by Set,
+// and leaves the cache once it expires.
+func TestSetTTLNoLRU(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 4})
+	defer m.Close()
+	m.SetTTLNoLRU("pure", 0, 1, time.Hour)
+	for i := 0; i < 10; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	if v, ok := m.GetOK("pure"); !ok || v != 0 {
+		t.Fatalf("GetOK(pure) = %v, %v after filling the cache; want 0, true", v, ok)
+	}
+	m.RangeLRU(func(key string, _ interface{}) bool {
+		if key == "pure" {
+			t.Error("RangeLRU visited the pure TTL entry")
+		}
+		return true
+	})
+
+	m.Set("pure", 1, 1)
+	withNode(t, m, "pure", func(node *Nodes) {
+		if !node.noLRU {
+			t.Error("Set turned pure into an LRU entry")
+		}
+		node.expiredAt = time.Now().Unix() - 1
+	})
+	m.pool[0].cleanExpired()
+	if _, ok := m.pool[0].pool["pure"]; ok {
+		t.Fatal("expired pure TTL entry is still stored")
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReady checks that Ready follows Drain and Undrain, reports a cache
+// without shards as not ready, and stays false once the cache is closed.
+func TestReady(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 10})
+	if !m.Ready() {
+		t.Fatal("Ready() = false for a new cache")
+	}
+	m.Drain()
+	if m.Ready() {
+		t.Fatal("Ready() = true while draining")
+	}
+	m.Undrain()
+	if !m.Ready() {
+		t.Fatal("Ready() = false after Undrain")
+	}
+
+	m.poolMut.Lock()
+	pool := m.pool
+	m.pool = nil
+	m.poolMut.Unlock()
+	if m.Ready() {
+		t.Fatal("Ready() = true without shards")
+	}
+	m.poolMut.Lock()
+	m.pool = pool
+	m.poolMut.Unlock()
+
+	m.Close()
+	m.Undrain()
+	if m.Ready() {
+		t.Fatal("Ready() = true after Close and Undrain")
+	}
+}
+
+// TestLen checks that Len counts live entries across shards, leaving out
+// expired entries and those invalidated by BumpEpoch before they are removed.
+func TestLen(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 12; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	m.SetTTLNoLRU("pure", 0, 1, time.Hour)
+	if n := m.Len(); n != 13 {
+		t.Fatalf("Len() = %d, want 13", n)
+	}
+
+	withNode(t, m, "key0", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+	if n := m.Len(); n != 12 {
+		t.Fatalf("Len() = %d with an expired entry, want 12", n)
+	}
+	m.Remove("key1")
+	if n := m.Len(); n != 11 {
+		t.Fatalf("Len() = %d after a Remove, want 11", n)
+	}
+	m.BumpEpoch()
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Len() = %d after BumpEpoch, want 0", n)
+	}
+}
+
+// TestEvictionCandidates checks that EvictionCandidates proposes the coldest
+// entries in eviction order, leaving out pinned and pure TTL entries and
+// proposing promoted ones last, and that it removes nothing.
+func TestEvictionCandidates(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.SetTTLNoLRU("pure", 0, 1, time.Hour)
+	for i := 0; i < 6; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	m.Get("key0")
+	m.Pin("key1")
+
+	if got, want := fmt.Sprint(m.EvictionCandidates(3)), "[key2 key3 key4]"; got != want {
+		t.Fatalf("EvictionCandidates(3) = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(m.EvictionCandidates(10)), "[key2 key3 key4 key5 key0]"; got != want {
+		t.Fatalf("EvictionCandidates(10) = %s, want %s", got, want)
+	}
+	if got := m.EvictionCandidates(0); got != nil {
+		t.Fatalf("EvictionCandidates(0) = %v, want nil", got)
+	}
+	if n := m.Len(); n != 7 {
+		t.Fatalf("Len() = %d after EvictionCandidates, want 7", n)
+	}
+}
+
+// TestEvictionCandidatesMerge checks that the proposals of several shards are
+// merged in eviction order, here the access counts of LFU.
+func TestEvictionCandidatesMerge(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10, Policy: PolicyLFU})
+	defer m.Close()
+	var want []string
+	for i := 0; i < 8; i++ {
+		key := fmt.Sprintf("key%d", i)
+		m.Set(key, i, 1)
+		for j := 0; j < i; j++ {
+			m.Get(key)
+		}
+		want = append(want, key)
+	}
+	if got := m.EvictionCandidates(8); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("EvictionCandidates(8) = %v, want %v", got, want)
+	}
+}
+
+// TestClear checks that Clear empties every shard, releases the removed
+// values, discards buffered writes, and leaves the cache usable.
+func TestClear(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10, CloseOnEvict: true, WriteCoalesceWindow: time.Hour})
+	defer m.Close()
+	closer := &closeCounter{}
+	m.Set("closer", closer, 1)
+	for i := 0; i < 12; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	m.flush(m.coalescer)
+	m.Set("buffered", 1, 1)
+
+	m.Clear()
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Len() = %d after Clear, want 0", n)
+	}
+	if v, ok := m.GetOK("buffered"); ok {
+		t.Fatalf("GetOK(buffered) = %v, true after Clear; want a miss", v)
+	}
+	if closer.closed != 1 {
+		t.Fatalf("cleared value closed %d times, want 1", closer.closed)
+	}
+	for _, size := range m.ShardSizes() {
+		if size != 0 {
+			t.Fatalf("ShardSizes() = %v after Clear, want all zero", m.ShardSizes())
+		}
+	}
+
+	m.Set("after", 1, 1)
+	if v, ok := m.GetOK("after"); !ok || v != 1 {
+		t.Fatalf("GetOK(after) = %v, %v; want 1, true", v, ok)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSetReplacesLikeSetTTL checks that Set and SetTTL treat an existing
+// entry alike: both give it the new expiry and size and promote it, and both
+// replace an expired entry without leaking its cost.
+func TestSetReplacesLikeSetTTL(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 3})
+	defer m.Close()
+	m.SetTTL("a", 1, 2, time.Minute)
+	m.Set("b", 2, 2)
+	m.Set("c", 3, 2)
+
+	m.Set("a", 10, 5)
+	if ttl, ok := m.TTL("a"); !ok || ttl <= defaultTTL-2*time.Second {
+		t.Fatalf("TTL(a) = %v, %v after Set; want %v", ttl, ok, defaultTTL)
+	}
+	m.SetTTL("b", 20, 1, time.Minute)
+	if ttl, ok := m.TTL("b"); !ok || ttl > time.Minute {
+		t.Fatalf("TTL(b) = %v, %v after SetTTL; want a minute", ttl, ok)
+	}
+	if c := m.Cost(); c != 8 {
+		t.Fatalf("Cost() = %d after the updates, want 8", c)
+	}
+
+	// a and b were promoted by their updates, leaving c the coldest.
+	m.Set("d", 4, 1)
+	if _, ok := m.Peek("c"); ok {
+		t.Fatal("c survived, want it evicted as the least recently used")
+	}
+
+	for _, set := range []func(){
+		func() { m.Set("a", 11, 3) },
+		func() { m.SetTTL("a", 11, 3, time.Hour) },
+	} {
+		withNode(t, m, "a", func(node *Nodes) {
+			node.expiredAt = time.Now().Unix() - 1
+		})
+		set()
+		if v, ok := m.GetOK("a"); !ok || v != 11 {
+			t.Fatalf("GetOK(a) = %v, %v after replacing the expired entry; want 11, true", v, ok)
+		}
+		if c := m.Cost(); c != 5 {
+			t.Fatalf("Cost() = %d after replacing the expired entry, want 5", c)
+		}
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPeek checks that Peek reads entries without promoting them or marking
+// them used, leaves expired entries for the cleaner, and is counted in
+// neither Stats nor the access log.
+func TestPeek(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 2, AccessLogSize: 10})
+	defer m.Close()
+	m.Set("old", 1, 1)
+	m.Set("new", 2, 1)
+	withNode(t, m, "old", func(node *Nodes) {
+		node.lastUsed = 1
+	})
+
+	if v, ok := m.Peek("old"); !ok || v != 1 {
+		t.Fatalf("Peek(old) = %v, %v; want 1, true", v, ok)
+	}
+	withNode(t, m, "old", func(node *Nodes) {
+		if node.lastUsed != 1 {
+			t.Error("Peek marked old as used")
+		}
+	})
+	if _, ok := m.Peek("missing"); ok {
+		t.Fatal("Peek(missing) reported the key present")
+	}
+	if s := m.Stats(); s.Hits != 0 || s.Misses != 0 {
+		t.Fatalf("Stats() = %d hits, %d misses after Peeks; want none", s.Hits, s.Misses)
+	}
+	if n := len(m.RecentOps()); n != 2 {
+		t.Fatalf("access log holds %d operations, want only the 2 Sets", n)
+	}
+
+	m.Set("newest", 3, 1)
+	if _, ok := m.Peek("old"); ok {
+		t.Fatal("old survived, want Peek to have left it the least recently used")
+	}
+
+	withNode(t, m, "new", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+	if _, ok := m.Peek("new"); ok {
+		t.Fatal("Peek(new) reported an expired entry present")
+	}
+	if _, ok := m.pool[0].pool["new"]; !ok {
+		t.Fatal("Peek removed the expired entry, want it left for the cleaner")
+	}
+}
+
+// TestExpirePersist checks that Expire and Persist change only an entry's
+// expiry, leaving its value and LRU position alone, that a persisted entry
+// survives the cleaner, and that both report missing or expired keys.
+func TestExpirePersist(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 2})
+	defer m.Close()
+	m.Set("a", 1, 1)
+	m.Set("b", 2, 1)
+
+	if !m.Expire("a", time.Minute) {
+		t.Fatal("Expire(a) = false")
+	}
+	if ttl, ok := m.TTL("a"); !ok || ttl > time.Minute || ttl < time.Minute-2*time.Second {
+		t.Fatalf("TTL(a) = %v, %v after Expire; want a minute", ttl, ok)
+	}
+	if !m.Persist("b") {
+		t.Fatal("Persist(b) = false")
+	}
+	if ttl, ok := m.TTL("b"); !ok || ttl != NoExpiry {
+		t.Fatalf("TTL(b) = %v, %v after Persist; want NoExpiry", ttl, ok)
+	}
+	if !m.Expire("b", 0) {
+		t.Fatal("Expire(b, 0) = false")
+	}
+	if ttl, _ := m.TTL("b"); ttl != NoExpiry {
+		t.Fatalf("TTL(b) = %v after Expire(b, 0), want NoExpiry", ttl)
+	}
+
+	// a was not promoted by Expire, so it is still the one evicted.
+	m.Set("c", 3, 1)
+	if _, ok := m.Peek("a"); ok {
+		t.Fatal("a survived, want Expire to have left it the least recently used")
+	}
+	if v, ok := m.Peek("b"); !ok || v != 2 {
+		t.Fatalf("Peek(b) = %v, %v; want 2, true", v, ok)
+	}
+
+	withNode(t, m, "c", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+	if m.Expire("c", time.Hour) || m.Persist("c") || m.Expire("missing", time.Hour) || m.Persist("missing") {
+		t.Fatal("Expire or Persist found an expired or missing key")
+	}
+	m.pool[0].cleanExpired()
+	if _, ok := m.Peek("b"); !ok {
+		t.Fatal("the cleaner removed persisted b")
+	}
+}
+
+// TestGetMultiPartition checks that GetMultiPartition maps the keys found,
+// nil values included, to their values, and lists missing and expired keys
+// once each, in the order they first appear.
+func TestGetMultiPartition(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	m.Set("a", 1, 1)
+	m.Set("b", 2, 1)
+	m.Set("nil", nil, 1)
+	m.Set("expired", 3, 1)
+	withNode(t, m, "expired", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+
+	hits, misses := m.GetMultiPartition([]string{"z", "a", "expired", "nil", "y", "a", "z", "b"})
+	if got, want := fmt.Sprint(hits), "map[a:1 b:2 nil:<nil>]"; got != want {
+		t.Fatalf("hits = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(misses), "[z expired y]"; got != want {
+		t.Fatalf("misses = %s, want %s", got, want)
+	}
+
+	hits, misses = m.GetMultiPartition(nil)
+	if len(hits) != 0 || misses != nil {
+		t.Fatalf("GetMultiPartition(nil) = %v, %v; want nothing", hits, misses)
+	}
+}
+
+// TestGetTTL checks that GetTTL reports the remaining lifetime of an entry,
+// NoExpiry for one that never expires, and false for missing and expired
+// keys, leaving an expired entry in place to be removed lazily.
+func TestGetTTL(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.SetTTL("minute", 1, 1, time.Minute)
+	m.SetTTL("forever", 2, 1, 0)
+	m.SetTTL("expired", 3, 1, time.Minute)
+	withNode(t, m, "expired", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+
+	if ttl, ok := m.GetTTL("minute"); !ok || ttl > time.Minute || ttl < time.Minute-2*time.Second {
+		t.Fatalf("GetTTL(minute) = %v, %v; want a minute", ttl, ok)
+	}
+	if ttl, ok := m.GetTTL("forever"); !ok || ttl != NoExpiry {
+		t.Fatalf("GetTTL(forever) = %v, %v; want NoExpiry", ttl, ok)
+	}
+	for _, key := range []string{"expired", "missing"} {
+		if ttl, ok := m.GetTTL(key); ok || ttl != 0 {
+			t.Fatalf("GetTTL(%s) = %v, %v; want 0, false", key, ttl, ok)
+		}
+	}
+	if _, ok := m.pool[0].pool["expired"]; !ok {
+		t.Fatal("GetTTL removed the expired entry, want it left in place")
+	}
+}
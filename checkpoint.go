@@ -0,0 +1,83 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checkpoint writes a snapshot of the cache to Config.CheckpointPath,
+// atomically replacing the previous checkpoint: the snapshot is written and
+// synced to a temporary file in the same directory, which is then renamed
+// over the checkpoint. It returns ErrNoCheckpointPath if no path is
+// configured, and the error of Snapshot or of the file operations otherwise,
+// in which case the previous checkpoint is left intact.
+func (m *CacheManager) Checkpoint() error {
+	if m.checkpointPath == "" {
+		return ErrNoCheckpointPath
+	}
+
+	dir, base := filepath.Split(m.checkpointPath)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := m.Snapshot(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.checkpointPath)
+}
+
+// restoreCheckpoint loads the checkpoint at Config.CheckpointPath, if there is
+// one. A missing or unreadable checkpoint leaves the cache empty, and a
+// damaged one is restored up to the first entry that fails to decode.
+func (m *CacheManager) restoreCheckpoint() {
+	f, err := os.Open(m.checkpointPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	m.Restore(bufio.NewReader(f))
+}
+
+// checkpointEvery writes a checkpoint every interval, and a last one when the
+// manager's done channel is closed. A failed checkpoint is retried on the
+// next tick.
+func (m *CacheManager) checkpointEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Checkpoint()
+		case <-m.done:
+			m.Checkpoint()
+			return
+		}
+	}
+}
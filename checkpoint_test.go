@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCheckpoint writes a checkpoint explicitly and checks that a new cache
+// on the same path restores it, that a failed checkpoint leaves the previous
+// one intact with no temporary file behind, and that Checkpoint needs a path.
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.ckpt")
+	m := New(&Config{ShardCap: 2, NodeCap: 10, CheckpointPath: path})
+	defer m.Close()
+	m.SetTTL("text", "hello", 3, time.Hour)
+	m.Set("number", 7, 1)
+	if err := m.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Set("bad", unregistered{1}, 1)
+	if err := m.Checkpoint(); !errors.Is(err, ErrUnregisteredType) {
+		t.Fatalf("Checkpoint with an unregistered type = %v, want ErrUnregisteredType", err)
+	}
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("checkpoint directory holds %d files, want only the checkpoint", len(files))
+	}
+
+	restored := New(&Config{ShardCap: 2, NodeCap: 10, CheckpointPath: path})
+	defer restored.Close()
+	if v := restored.Get("text"); v != "hello" {
+		t.Errorf("Get(text) = %#v after restoring the checkpoint, want hello", v)
+	}
+	if v := restored.Get("number"); v != 7 {
+		t.Errorf("Get(number) = %#v after restoring the checkpoint, want 7", v)
+	}
+	if _, ok := restored.Peek("bad"); ok {
+		t.Error("the failed checkpoint was restored")
+	}
+
+	none := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer none.Close()
+	if err := none.Checkpoint(); !errors.Is(err, ErrNoCheckpointPath) {
+		t.Fatalf("Checkpoint without a path = %v, want ErrNoCheckpointPath", err)
+	}
+}
+
+// TestCheckpointOnClose checks that a cache with a CheckpointInterval writes
+// a last checkpoint when it is closed, so writes since the last tick survive.
+func TestCheckpointOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.ckpt")
+	m := New(&Config{ShardCap: 2, NodeCap: 10, CheckpointPath: path, CheckpointInterval: time.Hour})
+	m.Set("key", "value", 1)
+	m.Close()
+
+	restored := New(&Config{ShardCap: 2, NodeCap: 10, CheckpointPath: path})
+	defer restored.Close()
+	if v := restored.Get("key"); v != "value" {
+		t.Fatalf("Get(key) = %#v after Close, want value", v)
+	}
+}
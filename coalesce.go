@@ -0,0 +1,206 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"time"
+)
+
+// pendingWrite is a Set or SetTTL call buffered by write coalescing. Its value
+// is held as it will be stored, already encoded, with the size resolved by
+// costOf and encode.
+type pendingWrite struct {
+	val    interface{}
+	size   uint64
+	ttl    time.Duration
+	hasTTL bool
+
+	// weight is the eviction weight given by SetWeighted, or nil.
+	weight *uint64
+
+	// seq identifies the write, so that a flush only discards the
+	// buffered writes it applied.
+	seq uint64
+}
+
+// coalescer holds the latest buffered write of each key until it is flushed.
+type coalescer struct {
+	mut     sync.Mutex
+	pending map[string]pendingWrite
+	seq     uint64
+}
+
+// coalesce buffers w as the latest write of key and reports whether it did.
+// Writes are not buffered when coalescing is disabled or the cache is draining,
+// and writes of a read-only key are dropped rather than buffered, so that they
+// never become visible to reads. The value of a buffered write is encoded
+// right away, as set would, so that reads see it as they will once it is
+// applied.
+func (m *CacheManager) coalesce(key string, w pendingWrite) bool {
+	c := m.coalescer
+	if c == nil || m.draining.Load() {
+		return false
+	}
+	if m.readOnly(key) {
+		return true
+	}
+	w.size = m.costOf(w.val, w.size)
+	w.val, w.size = m.encode(key, w.val, w.size)
+
+	c.mut.Lock()
+	c.seq++
+	w.seq = c.seq
+	c.pending[key] = w
+	c.mut.Unlock()
+	return true
+}
+
+// pendingValue returns the value of the buffered write of key, if any,
+// evaluating it if it is a lazy value stored by SetLazy and decoding it if it
+// was encoded, as reads of stored entries do. A lazy value is evaluated only
+// once, whether it is read while buffered or after being applied.
+func (m *CacheManager) pendingValue(key string) (interface{}, bool) {
+	c := m.coalescer
+	if c == nil {
+		return nil, false
+	}
+
+	c.mut.Lock()
+	w, ok := c.pending[key]
+	c.mut.Unlock()
+	if !ok {
+		return nil, false
+	}
+	val := w.val
+	if lv, isLazy := val.(*lazyValue); isLazy {
+		val, _ = lv.resolve()
+	}
+	return m.decode(val), true
+}
+
+// dropPending discards the buffered write of key, if any.
+func (m *CacheManager) dropPending(key string) {
+	m.takeWrite(key, 0)
+}
+
+// takeWrite removes the buffered write of key from the coalescer and returns
+// it. A seq other than zero only takes the write if it is still the one with
+// that seq, so that a flush never applies a write that was dropped or
+// superseded after it read the buffer.
+func (m *CacheManager) takeWrite(key string, seq uint64) (pendingWrite, bool) {
+	c := m.coalescer
+	if c == nil {
+		return pendingWrite{}, false
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	w, ok := c.pending[key]
+	if !ok || (seq != 0 && w.seq != seq) {
+		return pendingWrite{}, false
+	}
+	delete(c.pending, key)
+	return w, true
+}
+
+// applyPending applies the buffered write of key, if any, to shard and
+// discards it, so that writes made under the shard lock, such as GetSet,
+// Merge or a transaction's, start from the value reads already see, and are
+// not overwritten by the next flush. The caller must hold the shard lock, and
+// call reserve once it is released.
+func (m *CacheManager) applyPending(shard *NodeShards, key string) {
+	if w, ok := m.takeWrite(key, 0); ok {
+		m.applyLocked(shard, key, w)
+	}
+}
+
+// flush applies every buffered write to the cache. A write stays buffered, and
+// visible to reads, until it has been applied; writes buffered while the flush
+// is in progress are kept for the next one.
+func (m *CacheManager) flush(c *coalescer) {
+	c.mut.Lock()
+	pending := make(map[string]pendingWrite, len(c.pending))
+	for key, w := range c.pending {
+		pending[key] = w
+	}
+	c.mut.Unlock()
+
+	for key, w := range pending {
+		m.apply(key, w)
+	}
+
+	c.mut.Lock()
+	for key, w := range pending {
+		if c.pending[key].seq == w.seq {
+			delete(c.pending, key)
+		}
+	}
+	c.mut.Unlock()
+}
+
+// apply stores the buffered write w of key like the Set, SetTTL or
+// SetWeighted call it buffered, without encoding its value again. The write
+// is only stored if it is still buffered once the shard is locked.
+func (m *CacheManager) apply(key string, w pendingWrite) {
+	if m.draining.Load() {
+		return
+	}
+	if !m.oversized(w.size) {
+		m.reserveFor(key, w.size)
+	}
+	if m.enableDynamicShardScaling {
+		m.dynamicShardScaling()
+	}
+
+	shard := m.lockKey(key)
+	if _, ok := m.takeWrite(key, w.seq); ok {
+		m.applyLocked(shard, key, w)
+	}
+	m.unlockKey(shard)
+}
+
+// applyLocked stores the buffered write w of key in shard as store would,
+// giving it the TTL of a matching TTLRule if it was buffered by Set, and
+// removing the entry stored under key if w exceeds MaxCost, as discard
+// does. The caller must hold the shard lock.
+func (m *CacheManager) applyLocked(shard *NodeShards, key string, w pendingWrite) {
+	m.logOp(OpSet, key, false)
+	now := time.Now().Unix()
+	if m.oversized(w.size) {
+		if node, ok := shard.pool[key]; ok && !(node.readOnly && !shard.stale(node, now)) {
+			shard.deleteNode(node, removalRemoved)
+		}
+		return
+	}
+
+	ttl, expiry := w.ttl, expiryFor(w.ttl)
+	if !w.hasTTL {
+		if rule, ok := m.ruleTTL(key); ok {
+			ttl, expiry = rule, expiryFor(rule)
+		} else {
+			ttl, expiry = 0, expiryFor(defaultTTL)
+		}
+	}
+	m.storeLocked(shard, key, w.val, w.size, expiry, ttl, now, w.weight)
+}
+
+// flushCoalesced applies the buffered writes every window until the manager's
+// done channel is closed, flushing once more before returning.
+func (m *CacheManager) flushCoalesced(c *coalescer, window time.Duration) {
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.flush(c)
+		case <-m.done:
+			m.flush(c)
+			return
+		}
+	}
+}
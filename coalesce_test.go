@@ -0,0 +1,145 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// shardWrites returns the number of writes made to the shards of m.
+func shardWrites(m *CacheManager) uint64 {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	var writes uint64
+	for _, shard := range m.pool {
+		writes += shard.writes.Load()
+	}
+	return writes
+}
+
+// TestCoalesceBurst bursts Sets of one key within a window and checks that
+// reads see the latest value throughout, and that the shard is written far
+// fewer times than Set was called.
+func TestCoalesceBurst(t *testing.T) {
+	m, err := NewWithOptions(WithShardCap(1), WithNodeCap(10), WithWriteCoalesceWindow(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		m.Set("telemetry", i, 1)
+		if v, ok := m.GetOK("telemetry"); !ok || v != i {
+			t.Fatalf("GetOK = %v, %v after Set(%d); want %d, true", v, ok, i, i)
+		}
+	}
+	if n := shardWrites(m); n != 0 {
+		t.Fatalf("shard written %d times within the window, want 0", n)
+	}
+
+	// Close applies the buffered write.
+	m.Close()
+	if n := shardWrites(m); n != 1 {
+		t.Fatalf("shard written %d times for 1000 Sets, want 1", n)
+	}
+	if node, ok := m.pool[0].pool["telemetry"]; !ok || node.Value != 999 {
+		t.Fatalf("stored node = %v, %v after the flush; want 999, true", node, ok)
+	}
+}
+
+// stringCodec encodes values with fmt.Sprint and decodes them as strings, so
+// that a decoded value can be told apart from the one written.
+type stringCodec struct{}
+
+func (stringCodec) Encode(value interface{}) ([]byte, error) {
+	return []byte(fmt.Sprint(value)), nil
+}
+
+func (stringCodec) Decode(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+// TestCoalescedReadsMatchStoredReads checks that a buffered write reads the
+// same before and after it is applied, both for encoded values and for lazy
+// values, whose computation runs once.
+func TestCoalescedReadsMatchStoredReads(t *testing.T) {
+	m := New(&Config{
+		ShardCap:            1,
+		NodeCap:             10,
+		Codec:               stringCodec{},
+		EncodeValues:        true,
+		WriteCoalesceWindow: time.Hour,
+	})
+	defer m.Close()
+
+	m.Set("encoded", 42, 0)
+	if v := m.Get("encoded"); v != "42" {
+		t.Fatalf("buffered Get(encoded) = %#v, want \"42\"", v)
+	}
+
+	var runs atomic.Int32
+	m.SetLazy("lazy", func() (interface{}, uint64) {
+		runs.Add(1)
+		return 7, 1
+	}, time.Hour)
+	if v := m.Get("lazy"); v != 7 {
+		t.Fatalf("buffered Get(lazy) = %#v, want 7", v)
+	}
+
+	m.flush(m.coalescer)
+	if v := m.Get("encoded"); v != "42" {
+		t.Fatalf("stored Get(encoded) = %#v, want \"42\"", v)
+	}
+	if v := m.Get("lazy"); v != 7 {
+		t.Fatalf("stored Get(lazy) = %#v, want 7", v)
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("lazy value computed %d times, want 1", n)
+	}
+}
+
+// TestCoalescedReadModifyWrite buffers a Set of each key and checks that
+// GetSet, Merge and a transaction start from the buffered value, and that
+// their writes survive the flush.
+func TestCoalescedReadModifyWrite(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, WriteCoalesceWindow: time.Hour})
+	defer m.Close()
+	sum := func(existing, incoming interface{}) interface{} {
+		return existing.(int) + incoming.(int)
+	}
+
+	m.Set("k", "a", 1)
+	if old, existed := m.GetSet("k", "b", 1); !existed || old != "a" {
+		t.Errorf("GetSet = %v, %v; want the buffered a, true", old, existed)
+	}
+	m.Set("n", 1, 1)
+	m.Merge("n", 10, 1, sum)
+	m.Set("x", 5, 1)
+	m.Set("gone", 1, 1)
+	err := m.Transact([]string{"x", "gone"}, func(tx Tx) {
+		if v, ok := tx.Get("x"); !ok || v != 5 {
+			t.Errorf("tx.Get(x) = %v, %v; want the buffered 5, true", v, ok)
+		}
+		tx.Set("x", 6, 1)
+		tx.Remove("gone")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.flush(m.coalescer)
+	for key, want := range map[string]interface{}{"k": "b", "n": 11, "x": 6} {
+		if v, ok := m.Peek(key); !ok || v != want {
+			t.Errorf("Peek(%s) = %v, %v after the flush; want %v, true", key, v, ok, want)
+		}
+	}
+	if _, ok := m.Peek("gone"); ok {
+		t.Error("a key removed in a transaction came back with the flush")
+	}
+}
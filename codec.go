@@ -0,0 +1,164 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"math"
+	"time"
+)
+
+// Codec converts values to and from the bytes stored by the cache when
+// Config.EncodeValues is enabled.
+type Codec interface {
+	// Encode returns the byte representation of value.
+	Encode(value interface{}) ([]byte, error)
+
+	// Decode rebuilds a value from bytes produced by Encode.
+	Decode(data []byte) (interface{}, error)
+}
+
+// Type tags written as the first byte of data encoded by GobCodec.
+const (
+	tagString byte = iota + 1
+	tagBytes
+	tagInt
+	tagInt64
+	tagUint64
+	tagFloat64
+	tagBool
+	tagGob
+)
+
+// GobCodec is the default Codec. Strings, byte slices, booleans, int, int64,
+// uint64 and float64 values are encoded directly behind a one-byte type tag.
+// Any other value is encoded with encoding/gob, so its concrete type must be
+// registered with gob.Register before it is stored.
+type GobCodec struct{}
+
+// gobEnvelope wraps values encoded with gob so that their concrete type is
+// transmitted along with them.
+type gobEnvelope struct {
+	Value interface{}
+}
+
+// Encode returns the tagged byte representation of value.
+func (GobCodec) Encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return append([]byte{tagString}, v...), nil
+	case []byte:
+		return append([]byte{tagBytes}, v...), nil
+	case int:
+		return binary.AppendVarint([]byte{tagInt}, int64(v)), nil
+	case int64:
+		return binary.AppendVarint([]byte{tagInt64}, v), nil
+	case uint64:
+		return binary.AppendUvarint([]byte{tagUint64}, v), nil
+	case float64:
+		return binary.BigEndian.AppendUint64([]byte{tagFloat64}, math.Float64bits(v)), nil
+	case bool:
+		if v {
+			return []byte{tagBool, 1}, nil
+		}
+		return []byte{tagBool, 0}, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(tagGob)
+	if err := gob.NewEncoder(&buf).Encode(&gobEnvelope{Value: value}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode rebuilds a value from bytes produced by Encode.
+func (GobCodec) Decode(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, errors.New("cerebru: cannot decode empty value")
+	}
+
+	payload := data[1:]
+	switch data[0] {
+	case tagString:
+		return string(payload), nil
+	case tagBytes:
+		return append([]byte(nil), payload...), nil
+	case tagInt:
+		v, _ := binary.Varint(payload)
+		return int(v), nil
+	case tagInt64:
+		v, _ := binary.Varint(payload)
+		return v, nil
+	case tagUint64:
+		v, _ := binary.Uvarint(payload)
+		return v, nil
+	case tagFloat64:
+		if len(payload) != 8 {
+			return nil, errors.New("cerebru: malformed float64 value")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), nil
+	case tagBool:
+		return len(payload) > 0 && payload[0] == 1, nil
+	case tagGob:
+		var env gobEnvelope
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&env); err != nil {
+			return nil, err
+		}
+		return env.Value, nil
+	}
+	return nil, errors.New("cerebru: unknown value encoding")
+}
+
+// encodedValue marks a value stored in its encoded form, so that reads know
+// to decode it.
+type encodedValue []byte
+
+// encode converts the value stored under key into its stored form: encoded when
+// value encoding is enabled, then compressed when compression applies to it.
+// The size of an encoded or compressed value is its exact length in bytes.
+// Values that cannot be encoded, and lazy values, are stored as they are.
+// The time spent is added to Stats.EncodeTime.
+func (m *CacheManager) encode(key string, val interface{}, size uint64) (interface{}, uint64) {
+	if _, ok := val.(*lazyValue); ok {
+		return val, size
+	}
+	if m.codec == nil && m.shouldCompress == nil && m.compressMinSize == 0 {
+		return val, size
+	}
+	start := time.Now()
+	defer func() { m.stats.encodeNanos.Add(uint64(time.Since(start))) }()
+	orig := val
+
+	if m.codec != nil {
+		if data, err := m.codec.Encode(val); err == nil {
+			val, size = encodedValue(data), uint64(len(data))
+		}
+	}
+	return m.compress(key, orig, val, size)
+}
+
+// decode returns val in its original form, decompressing and decoding it if it
+// was stored compressed or encoded. A value that fails to decode is reported as nil.
+func (m *CacheManager) decode(val interface{}) interface{} {
+	if cv, ok := val.(compressedValue); ok {
+		val = decompress(cv)
+	}
+
+	ev, ok := val.(encodedValue)
+	if !ok || m.codec == nil {
+		return val
+	}
+
+	decoded, err := m.codec.Decode(ev)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
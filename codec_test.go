@@ -0,0 +1,62 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+// point is a struct value that GobCodec must encode with encoding/gob.
+type point struct{ X, Y int }
+
+func init() {
+	gob.Register(point{})
+}
+
+// TestGobCodecRoundTrip encodes values of every type GobCodec tags directly,
+// and a registered struct, and checks that they decode to equal values.
+func TestGobCodecRoundTrip(t *testing.T) {
+	for _, value := range []interface{}{
+		"text", []byte{1, 2, 3}, 42, int64(-7), uint64(1 << 40), 3.5, true, false, point{1, 2},
+	} {
+		data, err := GobCodec{}.Encode(value)
+		if err != nil {
+			t.Fatalf("Encode(%#v): %v", value, err)
+		}
+		got, err := GobCodec{}.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode(Encode(%#v)): %v", value, err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("Decode(Encode(%#v)) = %#v", value, got)
+		}
+	}
+}
+
+// TestEncodeValues checks that a cache with EncodeValues stores values as
+// bytes sized by their encoding, and decodes them on read.
+func TestEncodeValues(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, EncodeValues: true})
+	defer m.Close()
+	m.Set("text", "hello", 1000)
+	m.Set("point", point{3, 4}, 0)
+
+	if v := m.Get("text"); v != "hello" {
+		t.Errorf("Get(text) = %#v, want hello", v)
+	}
+	if v := m.Get("point"); v != (point{3, 4}) {
+		t.Errorf("Get(point) = %#v, want point{3, 4}", v)
+	}
+
+	m.poolMut.RLock()
+	node := m.pool[0].pool["text"]
+	m.poolMut.RUnlock()
+	if _, ok := node.Value.(encodedValue); !ok || node.nodeSize != uint64(len("hello")+1) {
+		t.Errorf("text stored as %T of size %d, want encoded bytes of size 6", node.Value, node.nodeSize)
+	}
+}
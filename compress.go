@@ -0,0 +1,87 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// Kinds of values held, in compressed form, by a compressedValue.
+const (
+	compressedBytes byte = iota
+	compressedString
+	compressedEncoded
+)
+
+// compressedValue marks a value stored compressed, so that reads know to
+// decompress it. kind records the type the value is restored to.
+type compressedValue struct {
+	data []byte
+	kind byte
+}
+
+// compress compresses val, as produced by encode, when compression applies to
+// the entry. ShouldCompress decides if it is set; otherwise values of at least
+// CompressMinSize are compressed. Only byte slices, strings and encoded values
+// can be compressed, and a value is kept as it is unless compressing it makes
+// it smaller. The size of a compressed value is its compressed length.
+func (m *CacheManager) compress(key string, orig, val interface{}, size uint64) (interface{}, uint64) {
+	if m.shouldCompress != nil {
+		if !m.shouldCompress(key, orig, size) {
+			return val, size
+		}
+	} else if m.compressMinSize == 0 || size < m.compressMinSize {
+		return val, size
+	}
+
+	var raw []byte
+	var kind byte
+	switch v := val.(type) {
+	case []byte:
+		raw, kind = v, compressedBytes
+	case string:
+		raw, kind = []byte(v), compressedString
+	case encodedValue:
+		raw, kind = v, compressedEncoded
+	default:
+		return val, size
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return val, size
+	}
+	if _, err := w.Write(raw); err != nil {
+		return val, size
+	}
+	if err := w.Close(); err != nil {
+		return val, size
+	}
+	if buf.Len() >= len(raw) {
+		return val, size
+	}
+	return compressedValue{data: buf.Bytes(), kind: kind}, uint64(buf.Len())
+}
+
+// decompress restores a compressedValue to the type it was stored as.
+// A value that fails to decompress is reported as nil.
+func decompress(cv compressedValue) interface{} {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(cv.data)))
+	if err != nil {
+		return nil
+	}
+
+	switch cv.kind {
+	case compressedString:
+		return string(raw)
+	case compressedEncoded:
+		return encodedValue(raw)
+	}
+	return raw
+}
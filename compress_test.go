@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// storedSize returns the size of the entry stored under key.
+func storedSize(t *testing.T, m *CacheManager, key string) uint64 {
+	t.Helper()
+	info, ok := m.Inspect(key)
+	if !ok {
+		t.Fatalf("no entry stored under %q", key)
+	}
+	return info.Size
+}
+
+// TestCompressMinSize checks that values of at least CompressMinSize are
+// stored compressed and read back intact, while smaller ones are not.
+func TestCompressMinSize(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, CompressMinSize: 100})
+	defer m.Close()
+	text := strings.Repeat("compressible ", 100)
+	raw := []byte(text)
+	m.Set("text", text, uint64(len(text)))
+	m.Set("raw", raw, uint64(len(raw)))
+	m.Set("small", "tiny", 4)
+
+	if v := m.Get("text"); v != text {
+		t.Error("Get(text) did not return the original string")
+	}
+	if v, _ := m.Get("raw").([]byte); !bytes.Equal(v, raw) {
+		t.Error("Get(raw) did not return the original bytes")
+	}
+	if size := storedSize(t, m, "text"); size >= uint64(len(text)) {
+		t.Errorf("text stored with size %d, want less than its %d bytes", size, len(text))
+	}
+	if size := storedSize(t, m, "small"); size != 4 {
+		t.Errorf("small stored with size %d, want it uncompressed at 4", size)
+	}
+}
+
+// TestShouldCompress checks that ShouldCompress overrides CompressMinSize.
+func TestShouldCompress(t *testing.T) {
+	m := New(&Config{
+		ShardCap:        1,
+		NodeCap:         10,
+		CompressMinSize: 1,
+		ShouldCompress: func(key string, value interface{}, size uint64) bool {
+			return strings.HasPrefix(key, "z:")
+		},
+	})
+	defer m.Close()
+	text := strings.Repeat("compressible ", 100)
+	m.Set("z:yes", text, uint64(len(text)))
+	m.Set("no", text, uint64(len(text)))
+
+	if size := storedSize(t, m, "z:yes"); size >= uint64(len(text)) {
+		t.Errorf("z:yes stored with size %d, want it compressed", size)
+	}
+	if size := storedSize(t, m, "no"); size != uint64(len(text)) {
+		t.Errorf("no stored with size %d, want it uncompressed", size)
+	}
+	if v := m.Get("z:yes"); v != text {
+		t.Error("Get(z:yes) did not return the original string")
+	}
+}
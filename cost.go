@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// Coster is implemented by values that know their own cache cost.
+// When a value passed to Set or SetTTL implements Coster and the size
+// argument is zero, the value's CacheCost is used as its size, keeping
+// cost logic next to the type instead of at every call site.
+type Coster interface {
+	CacheCost() uint64
+}
+
+// costOf returns the cost to account for val. A non-zero size passed by
+// the caller takes precedence over the value's own CacheCost, whose running
+// time is added to Stats.EstimateTime.
+func (m *CacheManager) costOf(val interface{}, size uint64) uint64 {
+	if size != 0 {
+		return size
+	}
+	c, ok := val.(Coster)
+	if !ok {
+		return 0
+	}
+	start := time.Now()
+	cost := c.CacheCost()
+	m.stats.estimateNanos.Add(uint64(time.Since(start)))
+	return cost
+}
+
+// oversized reports whether an entry of the given size can never fit within
+// the cost budget. Such entries are rejected rather than stored, so that a
+// single huge entry cannot wipe out the whole cache.
+func (m *CacheManager) oversized(size uint64) bool {
+	return size > m.maxCost
+}
+
+// discard removes the entry stored under key, if any. It is used when a write
+// is rejected so that the previous value does not linger as stale data. A
+// live read-only entry is kept instead, and ErrReadOnly returned.
+func (m *CacheManager) discard(key string) error {
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	node, exists := shard.pool[key]
+	if !exists {
+		return nil
+	}
+	if node.readOnly && !shard.stale(node, time.Now().Unix()) {
+		return ErrReadOnly
+	}
+	shard.deleteNode(node, removalRemoved)
+	return nil
+}
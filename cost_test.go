@@ -0,0 +1,323 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStrictCostIsGlobalCap writes and rewrites entries of varying sizes
+// from goroutines spread over every shard, and checks that the cache's cost
+// never exceeds MaxCost, even momentarily.
+func TestStrictCostIsGlobalCap(t *testing.T) {
+	const maxCost = 100
+	m := New(&Config{ShardCap: 8, NodeCap: 1000, MaxCost: maxCost, StrictCost: true})
+	defer m.Close()
+
+	var (
+		wg      sync.WaitGroup
+		stop    atomic.Bool
+		highest atomic.Uint64
+	)
+	go func() {
+		for !stop.Load() {
+			if c := m.Cost(); c > highest.Load() {
+				highest.Store(c)
+			}
+		}
+	}()
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := fmt.Sprintf("w%d:%d", w, i%50)
+				m.Set(key, i, uint64(1+(i*7+w)%40))
+			}
+		}(w)
+	}
+	wg.Wait()
+	stop.Store(true)
+
+	if c := highest.Load(); c > maxCost {
+		t.Fatalf("cost reached %d, want at most %d", c, maxCost)
+	}
+	if c := m.Cost(); c > maxCost {
+		t.Fatalf("Cost() = %d after the writes, want at most %d", c, maxCost)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStrictCostChargesUpdateGrowth updates an entry in a full cache and
+// checks that only its growth is charged, so that no other entry is evicted
+// while the grown entry still fits.
+func TestStrictCostChargesUpdateGrowth(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 100, MaxCost: 10, StrictCost: true})
+	defer m.Close()
+
+	m.Set("a", 1, 5)
+	m.Set("b", 2, 4)
+	if err := m.SetChecked("a", 3, 6); err != nil {
+		t.Fatalf("SetChecked(a) = %v, want nil", err)
+	}
+	if _, ok := m.Peek("b"); !ok {
+		t.Fatal("b was evicted, want it kept")
+	}
+	if c := m.Cost(); c != 10 {
+		t.Fatalf("Cost() = %d, want 10", c)
+	}
+
+	m.Set("a", 4, 8)
+	if _, ok := m.Peek("b"); ok {
+		t.Fatal("b survived, want it evicted to make room for a")
+	}
+	if v, _ := m.Peek("a"); v != 4 {
+		t.Fatalf("Peek(a) = %v, want 4", v)
+	}
+	if c := m.Cost(); c != 8 {
+		t.Fatalf("Cost() = %d, want 8", c)
+	}
+}
+
+// TestStrictCostRejectionLeavesEntry fills the budget with pinned entries and
+// checks that writes needing more room are rejected without changing the
+// entries they would have replaced or the cache's cost.
+func TestStrictCostRejectionLeavesEntry(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 100, MaxCost: 10, StrictCost: true})
+	defer m.Close()
+
+	m.Set("a", 1, 5)
+	m.Set("b", 2, 5)
+	m.Pin("a")
+	m.Pin("b")
+
+	if err := m.SetChecked("a", 3, 7); !errors.Is(err, ErrCostExceeded) {
+		t.Fatalf("SetChecked(a) = %v, want ErrCostExceeded", err)
+	}
+	if v, _ := m.Peek("a"); v != 1 {
+		t.Fatalf("Peek(a) = %v after the rejected write, want 1", v)
+	}
+	if err := m.SetChecked("c", 3, 1); !errors.Is(err, ErrCostExceeded) {
+		t.Fatalf("SetChecked(c) = %v, want ErrCostExceeded", err)
+	}
+	if _, ok := m.Peek("c"); ok {
+		t.Fatal("c was stored past MaxCost")
+	}
+	if err := m.Transact([]string{"b"}, func(tx Tx) {
+		if err := tx.Set("b", 3, 6); !errors.Is(err, ErrCostExceeded) {
+			t.Errorf("tx.Set(b) = %v, want ErrCostExceeded", err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if c := m.Cost(); c != 10 {
+		t.Fatalf("Cost() = %d, want 10", c)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestHighLoadStaysUnderMaxCost runs a scaled-down version of the high-load
+// example, whose entries carry their real size, and checks that the cache
+// evicts by cost to stay within MaxCost while serving the workload.
+func TestHighLoadStaysUnderMaxCost(t *testing.T) {
+	const maxCost = 16 * UnitKB
+	m := New(&Config{ShardCap: 8, NodeCap: 64, MaxCost: maxCost})
+	defer m.Close()
+	payload := strings.Repeat("x", 256)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 50; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < 20_000; i += 50 {
+				key := fmt.Sprintf("key:%d", i%2000)
+				if _, ok := m.GetOK(key); !ok {
+					val := fmt.Sprintf("value-%d:%s", i, payload)
+					m.Set(key, val, uint64(len(val)))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if cost := m.Cost(); cost > maxCost {
+		t.Fatalf("Cost() = %d, want at most MaxCost %d", cost, maxCost)
+	}
+	if m.Stats().Evictions == 0 {
+		t.Fatal("no evictions, want the workload to exceed MaxCost")
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// costly is a value that reports its own cache cost.
+type costly uint64
+
+func (c costly) CacheCost() uint64 { return uint64(c) }
+
+// TestCosterSizesEntries checks that a Coster value stored with a size of
+// zero is charged its CacheCost, and that an explicit size takes precedence.
+func TestCosterSizesEntries(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, MaxCost: 100})
+	defer m.Close()
+
+	m.Set("own", costly(30), 0)
+	if c := m.Cost(); c != 30 {
+		t.Fatalf("Cost() = %d after storing a Coster of 30, want 30", c)
+	}
+	m.Set("explicit", costly(30), 5)
+	if c := m.Cost(); c != 35 {
+		t.Fatalf("Cost() = %d after an explicit size of 5, want 35", c)
+	}
+
+	// A Coster larger than MaxCost is rejected like any oversized entry.
+	m.Set("own", costly(200), 0)
+	if _, ok := m.Peek("own"); ok {
+		t.Fatal("oversized Coster was stored")
+	}
+	if c := m.Cost(); c != 5 {
+		t.Fatalf("Cost() = %d after rejecting the oversized Coster, want 5", c)
+	}
+}
+
+// TestOversizedEntryRejected writes entries larger than MaxCost through Set
+// and SetTTL and checks that they are rejected, removing the entry stored
+// under their key, without evicting anything else.
+func TestOversizedEntryRejected(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10, MaxCost: 100})
+	defer m.Close()
+	for i := 0; i < 5; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 10)
+	}
+
+	m.Set("key0", "huge", 101)
+	m.SetTTL("key1", "huge", 101, time.Hour)
+	m.Set("new", "huge", 1000)
+
+	for _, key := range []string{"key0", "key1", "new"} {
+		if _, ok := m.Peek(key); ok {
+			t.Errorf("%s is present after an oversized write", key)
+		}
+	}
+	if n, c := m.Len(), m.Cost(); n != 3 || c != 30 {
+		t.Fatalf("Len() = %d, Cost() = %d; want the 3 other entries of 10", n, c)
+	}
+	if e := m.Stats().Evictions; e != 0 {
+		t.Fatalf("%d evictions, want oversized writes to evict nothing", e)
+	}
+}
+
+// TestMaxCostEvictsGloballyColdest fills a cache spread over several shards
+// up to MaxCost, and checks that a write over the budget evicts the coldest
+// entries of the whole cache, wherever they live, until it fits.
+func TestMaxCostEvictsGloballyColdest(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 100, MaxCost: 10})
+	defer m.Close()
+	now := time.Now().Unix()
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		m.Set(key, i, 1)
+		withNode(t, m, key, func(node *Nodes) {
+			node.lastUsed = now - 100 + int64(i)
+		})
+	}
+
+	m.Set("new", 10, 3)
+	if c := m.Cost(); c != 10 {
+		t.Fatalf("Cost() = %d, want the budget of 10", c)
+	}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := m.Peek(key); ok != (i >= 3) {
+			t.Errorf("Peek(%s) = %v, want only key0 to key2 evicted", key, ok)
+		}
+	}
+	if e := m.Stats().Evictions; e != 3 {
+		t.Fatalf("%d evictions, want 3", e)
+	}
+}
+
+// TestGlobalEvictionKeepsClockBits fills a PolicyClock cache of several
+// shards with read entries and checks that evicting the coldest entry across
+// shards clears reference bits only in the shard it evicts from.
+func TestGlobalEvictionKeepsClockBits(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 100, MaxCost: 16, Policy: PolicyClock})
+	defer m.Close()
+	for i := 0; i < 16; i++ {
+		key := fmt.Sprintf("key%d", i)
+		m.Set(key, i, 1)
+		m.Get(key)
+	}
+
+	m.Set("new", 16, 1)
+	if e := m.Stats().Evictions; e != 1 {
+		t.Fatalf("%d evictions, want 1", e)
+	}
+	var victim *NodeShards
+	for i := 0; i < 16; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := m.Peek(key); !ok {
+			victim = m.pool[m.shardIndex(key)]
+		}
+	}
+	if victim == nil {
+		t.Fatal("no key was evicted")
+	}
+	for i := 0; i < 16; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if m.pool[m.shardIndex(key)] == victim {
+			continue
+		}
+		withNode(t, m, key, func(node *Nodes) {
+			if !node.referenced {
+				t.Errorf("%s lost its reference bit in a shard not evicted from", key)
+			}
+		})
+	}
+}
+
+// TestRemoveReleasesCost removes entries of several shards and checks that
+// their sizes are given back to each shard and to the cache's cost, so that
+// the freed budget can be written again without evicting anything.
+func TestRemoveReleasesCost(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10, MaxCost: 40})
+	defer m.Close()
+	for i := 0; i < 8; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 5)
+	}
+	for i := 0; i < 4; i++ {
+		m.Remove(fmt.Sprintf("key%d", i))
+	}
+	if c := m.Cost(); c != 20 {
+		t.Fatalf("Cost() = %d after removing half the entries, want 20", c)
+	}
+	var shardTotal uint64
+	for _, shard := range m.pool {
+		shardTotal += shard.shardSize
+	}
+	if shardTotal != 20 {
+		t.Fatalf("shard sizes add up to %d, want 20", shardTotal)
+	}
+
+	for i := 0; i < 4; i++ {
+		m.Set(fmt.Sprintf("new%d", i), i, 5)
+	}
+	if e := m.Stats().Evictions; e != 0 {
+		t.Fatalf("%d evictions refilling the freed budget, want 0", e)
+	}
+}
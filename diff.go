@@ -0,0 +1,64 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"reflect"
+	"sort"
+)
+
+// CacheDiff describes how the live entries of one cache differ from those of
+// another, as reported by Diff. Each list of keys is sorted.
+type CacheDiff struct {
+	// Added holds the keys present only in the other cache.
+	Added []string
+
+	// Removed holds the keys present only in the receiver.
+	Removed []string
+
+	// Changed holds the keys present in both caches whose values, compared
+	// with reflect.DeepEqual, or sizes differ.
+	Changed []string
+}
+
+// Empty reports whether the diff found no differences.
+func (d CacheDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the live entries of m with those of other and reports the
+// keys added, removed and changed going from m to other. Entries are
+// collected as Snapshot would, so values are compared in their decoded form
+// and lazy values not computed yet are ignored; expiries are not compared.
+// It is meant for tests: snapshot a cache into another with Snapshot and
+// Restore, run operations on it, and Diff the two.
+func (m *CacheManager) Diff(other *CacheManager) CacheDiff {
+	before := make(map[string]snapshotEntry)
+	for _, entry := range m.snapshotEntries() {
+		before[entry.Key] = entry
+	}
+
+	var diff CacheDiff
+	for _, entry := range other.snapshotEntries() {
+		old, ok := before[entry.Key]
+		if !ok {
+			diff.Added = append(diff.Added, entry.Key)
+			continue
+		}
+		delete(before, entry.Key)
+		if old.Size != entry.Size || !reflect.DeepEqual(old.Value, entry.Value) {
+			diff.Changed = append(diff.Changed, entry.Key)
+		}
+	}
+	for key := range before {
+		diff.Removed = append(diff.Removed, key)
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
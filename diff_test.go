@@ -0,0 +1,53 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestDiff copies a cache with Snapshot and Restore, changes the copy, and
+// checks that Diff reports the added, removed and changed keys, sorted, and
+// nothing for identical caches or a changed expiry alone.
+func TestDiff(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 6; i++ {
+		m.Set(fmt.Sprintf("key%d", i), []int{i}, 1)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	other := New(&Config{ShardCap: 2, NodeCap: 10})
+	defer other.Close()
+	if err := other.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if d := m.Diff(other); !d.Empty() {
+		t.Fatalf("Diff() = %+v between a cache and its copy, want empty", d)
+	}
+
+	other.SetTTL("key0", []int{0}, 1, time.Minute)
+	other.Set("key1", []int{10}, 1)
+	other.Set("key2", []int{2}, 5)
+	other.Remove("key4")
+	other.Remove("key3")
+	other.Set("new", 1, 1)
+	other.Set("another", 2, 1)
+
+	d := m.Diff(other)
+	if got, want := fmt.Sprintf("%v %v %v", d.Added, d.Removed, d.Changed), "[another new] [key3 key4] [key1 key2]"; got != want {
+		t.Fatalf("Diff() = %s, want %s", got, want)
+	}
+	if d.Empty() {
+		t.Fatal("Empty() = true for a diff with changes")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "io"
+
+// removalReason describes why a node left the cache.
+type removalReason int
+
+const (
+	// removalEvicted marks nodes evicted by capacity or cost pressure.
+	removalEvicted removalReason = iota
+
+	// removalExpired marks nodes removed after their expiry passed.
+	removalExpired
+
+	// removalRemoved marks nodes deleted explicitly by the caller.
+	removalRemoved
+)
+
+// removal records a node that left a shard while its lock was held.
+type removal struct {
+	node   *Nodes
+	reason removalReason
+}
+
+// record queues a removed node so that it can be handed to the release hooks
+// once the shard lock is released, and counts expired nodes in the shard's
+// statistics. Nothing is queued if no hooks are installed.
+func (ns *NodeShards) record(node *Nodes, reason removalReason) {
+	if reason == removalExpired && ns.stats != nil {
+		ns.stats.expirations.Add(1)
+	}
+	if ns.dispose == nil {
+		return
+	}
+	ns.pending = append(ns.pending, removal{node: node, reason: reason})
+}
+
+// takePending returns and clears the removals queued while the lock was held.
+func (ns *NodeShards) takePending() []removal {
+	pending := ns.pending
+	ns.pending = nil
+	return pending
+}
+
+// unlock releases the shard's write lock and then hands every node removed
+// while it was held to the release hooks, so that hooks may re-enter the cache.
+func (ns *NodeShards) unlock() {
+	pending := ns.takePending()
+	ns.settle()
+	ns.recordHold()
+	ns.mut.Unlock()
+	if len(pending) > 0 {
+		ns.dispose(pending)
+	}
+}
+
+// dispose runs the release hooks for nodes that have left the cache: the
+// OnEvict and OnExpire callbacks, then CloseOnEvict. It must be called
+// without any shard lock held.
+func (m *CacheManager) dispose(removed []removal) {
+	for _, r := range removed {
+		var hook func(key string, value interface{})
+		switch r.reason {
+		case removalEvicted:
+			hook = m.onEvict
+		case removalExpired:
+			hook = m.onExpire
+		}
+		if hook != nil {
+			val := r.node.Value
+			if lv, ok := val.(*lazyValue); ok {
+				val, _ = lv.resolve()
+			}
+			hook(r.node.Key, m.decode(val))
+		}
+		if m.closeOnEvict {
+			if closer, ok := r.node.Value.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// closeCounter counts how many times it is closed.
+type closeCounter struct{ closed int }
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+// TestCloseOnEvict checks that CloseOnEvict closes values that leave the
+// cache by eviction or removal, and that nothing is closed without it.
+func TestCloseOnEvict(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		m := New(&Config{ShardCap: 1, NodeCap: 1, CloseOnEvict: enabled})
+		evicted, removed := &closeCounter{}, &closeCounter{}
+		m.Set("evicted", evicted, 1)
+		m.Set("removed", removed, 1)
+		m.Remove("removed")
+		m.Close()
+
+		want := 0
+		if enabled {
+			want = 1
+		}
+		if evicted.closed != want || removed.closed != want {
+			t.Errorf("CloseOnEvict %v: evicted closed %d times, removed %d; want %d", enabled, evicted.closed, removed.closed, want)
+		}
+	}
+}
+
+// TestOnEvictOnExpire checks that OnEvict reports entries evicted by capacity
+// and OnExpire entries found expired on read or swept by the cleaner, that
+// neither reports removed entries, and that the callbacks may use the cache.
+func TestOnEvictOnExpire(t *testing.T) {
+	var events []string
+	var m *CacheManager
+	m = New(&Config{
+		ShardCap: 1,
+		NodeCap:  2,
+		OnEvict: func(key string, value interface{}) {
+			events = append(events, fmt.Sprintf("evict %s %v %d", key, value, m.Len()))
+		},
+		OnExpire: func(key string, value interface{}) {
+			events = append(events, fmt.Sprintf("expire %s %v %d", key, value, m.Len()))
+		},
+	})
+	defer m.Close()
+
+	m.Set("evicted", 1, 1)
+	m.Set("read", 2, 1)
+	m.Set("swept", 3, 1)
+	m.Set("removed", 4, 1)
+	m.Remove("removed")
+	m.Set("read", 5, 1)
+	for _, key := range []string{"read", "swept"} {
+		withNode(t, m, key, func(node *Nodes) {
+			node.expiredAt = time.Now().Unix() - 1
+		})
+	}
+	m.Get("read")
+	m.pool[0].cleanExpired()
+
+	want := "[evict evicted 1 2 evict read 2 2 expire read 5 0 expire swept 3 0]"
+	if got := fmt.Sprint(events); got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "errors"
+
+var (
+	// ErrShardIndex is returned when a shard index is outside the
+	// range of shards currently held by the CacheManager.
+	ErrShardIndex = errors.New("cerebru: shard index out of range")
+
+	// ErrInvalidCapacity is returned when a capacity of zero or less
+	// is requested for a shard.
+	ErrInvalidCapacity = errors.New("cerebru: capacity must be positive")
+
+	// ErrInvariant is wrapped by the errors returned from Verify when the
+	// internal structures of a shard are found to be inconsistent.
+	ErrInvariant = errors.New("cerebru: invariant violated")
+
+	// ErrUnregisteredType is wrapped by the error returned from Snapshot
+	// when a cached value's concrete type has not been passed to RegisterType.
+	ErrUnregisteredType = errors.New("cerebru: value type not registered")
+
+	// ErrCrossShard is returned by Transact when its keys do not all live
+	// in the same shard. Give related keys a common hash tag, such as
+	// "{user:42}", to place them together.
+	ErrCrossShard = errors.New("cerebru: keys span multiple shards; use a common hash tag")
+
+	// ErrKeyNotInTx is returned by a Tx when it is used with a key that the
+	// transaction was not opened with.
+	ErrKeyNotInTx = errors.New("cerebru: key not part of the transaction")
+
+	// ErrTTLRequired is the panic value of Set when Config.RequireExplicitTTL
+	// is set and the key is not covered by a TTL rule.
+	ErrTTLRequired = errors.New("cerebru: Set without a TTL; use SetTTL")
+
+	// ErrNoCheckpointPath is returned by Checkpoint when Config.CheckpointPath
+	// is not set.
+	ErrNoCheckpointPath = errors.New("cerebru: no checkpoint path configured")
+
+	// ErrReadOnly is returned by SetChecked, SetReadOnly, Tx.Set and Rename
+	// when the key written holds a read-only entry stored by SetReadOnly.
+	ErrReadOnly = errors.New("cerebru: key is read-only")
+
+	// ErrCostExceeded is returned by SetChecked and Tx.Set when, with
+	// Config.StrictCost set, no room can be made within MaxCost for the
+	// write, because the entries that would have to be evicted are pinned.
+	// Any entry previously stored under the key is left as it was.
+	ErrCostExceeded = errors.New("cerebru: no room within MaxCost")
+
+	// ErrWeightUnsupported is returned by SetWeighted when the eviction
+	// policy ignores weights, as PolicyLRU and PolicyClock do.
+	ErrWeightUnsupported = errors.New("cerebru: eviction policy does not use weights")
+)
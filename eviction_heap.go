@@ -4,7 +4,10 @@
 // file copy or read online at https://opensource.org/license/mit
 package cerebru
 
-import "container/heap"
+import (
+	"container/heap"
+	"fmt"
+)
 
 // EvictionHeap is a min-heap of Nodes pointers, which allows for efficient
 // retrieval and removal of the least recently used nodes.
@@ -16,7 +19,8 @@ func (eh EvictionHeap) Len() int {
 }
 
 // Less reports whether the node at index next should sort before the node at index prev.
-// It compares the lastUsed timestamps of the nodes.
+// Pinned nodes sort last and probationary nodes sort before protected ones; otherwise
+// it compares the lastUsed timestamps of the nodes, breaking ties by insertion order.
 func (eh EvictionHeap) Less(next, prev int) bool {
 	if next >= eh.Len() || prev >= eh.Len() {
 		return false
@@ -30,7 +34,45 @@ func (eh EvictionHeap) Less(next, prev int) bool {
 		return true
 	}
 
-	return eh[next].lastUsed < eh[prev].lastUsed
+	return evictsBefore(eh[next], eh[prev])
+}
+
+// evictsBefore reports whether node a should be evicted before node b.
+// Pinned nodes sort last and probationary nodes sort before protected ones,
+// then the lightest and the least frequently used node sorts first; otherwise
+// the least recently used node sorts first, ties broken by insertion order.
+// Weights are only set under PolicyCost and access frequencies are only
+// counted under PolicyLFU.
+func evictsBefore(a, b *Nodes) bool {
+	if a.pinned != b.pinned {
+		return !a.pinned
+	}
+	if a.protected != b.protected {
+		return !a.protected
+	}
+	if a.weight != b.weight {
+		return a.weight < b.weight
+	}
+	if a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	if a.lastUsed != b.lastUsed {
+		return a.lastUsed < b.lastUsed
+	}
+	return a.seq < b.seq
+}
+
+// ranking returns a copy of the fields of n that evictsBefore compares, so
+// that nodes of different shards can be ordered after their locks are released.
+func (n *Nodes) ranking() Nodes {
+	return Nodes{
+		pinned:    n.pinned,
+		protected: n.protected,
+		weight:    n.weight,
+		freq:      n.freq,
+		lastUsed:  n.lastUsed,
+		seq:       n.seq,
+	}
 }
 
 // Pop removes and returns the node with the least recently used timestamp
@@ -42,31 +84,75 @@ func (eh *EvictionHeap) Pop() interface{} {
 		return nil
 	}
 	item := old[new-1]
+	old[new-1] = nil
+	item.heapIndex = -1
 	*eh = old[:new-1]
 	return item
 }
 
-// Swap exchanges the nodes at indices next and prev in the eviction heap.
+// Swap exchanges the nodes at indices next and prev in the eviction heap
+// and updates their heap indexes.
 func (eh EvictionHeap) Swap(next, prev int) {
 	if next < 0 || next >= len(eh) || prev < 0 || prev >= len(eh) {
 		return
 	}
 	eh[next], eh[prev] = eh[prev], eh[next]
+	eh[next].heapIndex = next
+	eh[prev].heapIndex = prev
 }
 
 // Push adds a new node to the eviction heap.
 func (eh *EvictionHeap) Push(node interface{}) {
-	*eh = append(*eh, node.(*Nodes))
+	n := node.(*Nodes)
+	n.heapIndex = len(*eh)
+	*eh = append(*eh, n)
 }
 
-// RemoveNode removes a specific node from the eviction heap.
-// It reinitializes the heap to maintain the heap property after removal.
+// RemoveNode removes a specific node from the eviction heap in O(log n),
+// locating it by its heap index. Nodes not in the heap are ignored.
 func (eh *EvictionHeap) RemoveNode(node *Nodes) {
-	for i, n := range *eh {
-		if n == node {
-			*eh = append((*eh)[:i], (*eh)[i+1:]...)
-			heap.Init(eh)
-			return
+	i := node.heapIndex
+	if i < 0 || i >= eh.Len() || (*eh)[i] != node {
+		return
+	}
+	heap.Remove(eh, i)
+}
+
+// holds reports whether node is in the heap, at its indexed position.
+func (eh EvictionHeap) holds(node *Nodes) bool {
+	i := node.heapIndex
+	return i >= 0 && i < len(eh) && eh[i] == node
+}
+
+// compact drops the nodes whose heap index was cleared to -1 from the heap,
+// restores the heap property in one pass and reindexes the remaining nodes.
+func (eh *EvictionHeap) compact() {
+	old := *eh
+	kept := old[:0]
+	for i, node := range old {
+		if node.heapIndex == i {
+			kept = append(kept, node)
+		}
+	}
+	for i := len(kept); i < len(old); i++ {
+		old[i] = nil
+	}
+
+	*eh = kept
+	for i, node := range kept {
+		node.heapIndex = i
+	}
+	heap.Init(eh)
+}
+
+// verify checks the heap property: no node sorts before its parent.
+// It returns an error naming the first offending index.
+func (eh EvictionHeap) verify() error {
+	for i := 1; i < len(eh); i++ {
+		parent := (i - 1) / 2
+		if eh.Less(i, parent) {
+			return fmt.Errorf("heap node %d sorts before its parent %d", i, parent)
 		}
 	}
+	return nil
 }
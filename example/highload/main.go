@@ -1,7 +1,13 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
 package main
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,36 +16,39 @@ import (
 	"github.com/bluespada/cerebru"
 )
 
+// payload pads every cached value so that entries carry a realistic size
+// and the MaxCost budget is actually exercised.
+var payload = strings.Repeat("x", 256)
+
 func main() {
+	const maxCost = 16 * cerebru.UnitKB
+
 	mem := cerebru.New(&cerebru.Config{
 		EnableCleaner:         true,
 		EnableDynamicSharding: false,
 		ShardCap:              8,
-		NodeCap:               8,
-		MaxCost:               500 * cerebru.UnitMB,
+		NodeCap:               64,
+		MaxCost:               maxCost,
 	})
 
 	var wg sync.WaitGroup
 	const totalRequests = 500_000_000
 	concurrency := 2_000
 
-	var cacheHits, cacheMisses int
 	start := time.Now()
 
 	apiRequest := func(i int) {
 		defer wg.Done()
 
 		key := fmt.Sprintf("key:%d", i)
-		res := mem.Get(key)
-		if res != nil {
-			cacheHits++
-		} else {
-			cacheMisses++
-			mem.Set(key, fmt.Sprintf("value-%d", i), 0)
+		if _, ok := mem.GetOK(key); !ok {
+			val := fmt.Sprintf("value-%d:%s", i, payload)
+			mem.Set(key, val, uint64(len(val)))
 		}
 
 		if i%1000 == 0 {
-			mem.Set(key, fmt.Sprintf("ttl-value-%d", i), 0)
+			val := fmt.Sprintf("ttl-value-%d:%s", i, payload)
+			mem.Set(key, val, uint64(len(val)))
 		}
 	}
 
@@ -53,13 +62,17 @@ func main() {
 	elapsed := time.Since(start)
 	fmt.Printf("Total time for %d requests: %s\n", totalRequests, elapsed)
 
-	hitRate := float64(cacheHits) / float64(totalRequests) * 100
-	missRate := float64(cacheMisses) / float64(totalRequests) * 100
+	stats := mem.Stats()
+	hitRate := float64(stats.Hits) / float64(totalRequests) * 100
+	missRate := float64(stats.Misses) / float64(totalRequests) * 100
 
-	fmt.Printf("Cache Hit Count: %d\n", cacheHits)
-	fmt.Printf("Cache Miss Count: %d\n", cacheMisses)
+	fmt.Printf("Cache Hit Count: %d\n", stats.Hits)
+	fmt.Printf("Cache Miss Count: %d\n", stats.Misses)
 	fmt.Printf("Cache Hit Rate: %.2f%%\n", hitRate)
 	fmt.Printf("Cache Miss Rate: %.2f%%\n", missRate)
+	fmt.Printf("Cache Evictions: %d\n", stats.Evictions)
+	fmt.Printf("Cache Expirations: %d\n", stats.Expirations)
+	fmt.Printf("Cache Cost: %d / %d bytes\n", mem.Cost(), maxCost)
 
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
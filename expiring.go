@@ -0,0 +1,51 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sort"
+	"time"
+)
+
+// ExpiringWithin returns the keys of the live entries that will expire within
+// the next d, soonest first, so that a background refresher can renew them
+// before they expire and avoid a burst of misses. Entries that never expire
+// are not returned. Expiries have a resolution of one second. It scans every
+// shard, so it is intended for periodic jobs rather than hot paths.
+func (m *CacheManager) ExpiringWithin(d time.Duration) []string {
+	type expiring struct {
+		key       string
+		expiredAt int64
+	}
+
+	m.poolMut.RLock()
+	now := time.Now()
+	deadline := now.Add(d).Unix()
+	var found []expiring
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		for key, node := range shard.pool {
+			if node.expiredAt == 0 || node.expiredAt > deadline || shard.stale(node, now.Unix()) {
+				continue
+			}
+			found = append(found, expiring{key: key, expiredAt: node.expiredAt})
+		}
+		shard.mut.RUnlock()
+	}
+	m.poolMut.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].expiredAt != found[j].expiredAt {
+			return found[i].expiredAt < found[j].expiredAt
+		}
+		return found[i].key < found[j].key
+	})
+	keys := make([]string, len(found))
+	for i, e := range found {
+		keys[i] = e.key
+	}
+	return keys
+}
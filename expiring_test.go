@@ -0,0 +1,39 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestExpiringWithin checks that ExpiringWithin lists the entries of every
+// shard expiring within the window, soonest first, leaving out those expiring
+// later, never expiring or already expired.
+func TestExpiringWithin(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	m.SetTTL("minutes", 1, 1, 3*time.Minute)
+	m.SetTTL("seconds", 2, 1, 30*time.Second)
+	m.SetTTL("minute", 3, 1, time.Minute)
+	m.SetTTL("hour", 4, 1, time.Hour)
+	m.SetTTL("forever", 5, 1, 0)
+	m.SetTTL("expired", 6, 1, time.Minute)
+	withNode(t, m, "expired", func(node *Nodes) {
+		node.expiredAt = time.Now().Unix() - 1
+	})
+
+	if got, want := fmt.Sprint(m.ExpiringWithin(5*time.Minute)), "[seconds minute minutes]"; got != want {
+		t.Fatalf("ExpiringWithin(5m) = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(m.ExpiringWithin(2*time.Hour)), "[seconds minute minutes hour]"; got != want {
+		t.Fatalf("ExpiringWithin(2h) = %s, want %s", got, want)
+	}
+	if got := m.ExpiringWithin(time.Second); len(got) != 0 {
+		t.Fatalf("ExpiringWithin(1s) = %v, want none", got)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"time"
+)
+
+// shardMutex is the lock of a shard: a *sync.RWMutex, or a *fairRWMutex when
+// Config.FairLocking is set.
+type shardMutex interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// newShardMutex returns the lock for a new shard.
+func newShardMutex(fair bool) shardMutex {
+	if fair {
+		return &fairRWMutex{}
+	}
+	return &sync.RWMutex{}
+}
+
+// fairWait is how long the oldest caller queued on a fairRWMutex waits
+// before the lock stops letting newly arriving callers take it ahead of the
+// queue.
+const fairWait = 100 * time.Microsecond
+
+// fairRWMutex is a read-write lock that bounds how long any caller, reader or
+// writer, can be overtaken. A caller that finds the lock free takes it, and
+// any other caller joins a queue. When the lock is released, the head of the
+// queue is woken to try again, competing with callers that just arrived,
+// which keeps the lock moving on busy CPUs. Once the head has waited longer
+// than fairWait, the lock is instead handed over in queue order, a writer
+// alone or the run of readers up to the next queued writer together, and
+// newly arriving callers queue behind. sync.RWMutex lets a stream of readers
+// overtake one another indefinitely while a writer is not waiting, and
+// releases every waiting reader ahead of writers that queued before them.
+type fairRWMutex struct {
+	mu sync.Mutex
+
+	// readers is the number of readers holding the lock.
+	readers int
+
+	// writer reports whether a writer holds the lock.
+	writer bool
+
+	// queue holds the waiting callers, oldest first.
+	queue []*fairWaiter
+}
+
+// fairWaiter is a caller queued on a fairRWMutex.
+type fairWaiter struct {
+	write bool
+
+	// since is when the caller first queued.
+	since time.Time
+
+	// ready is closed to wake the caller, and granted is set if the lock was
+	// handed to it rather than left for it to compete for.
+	ready   chan struct{}
+	granted bool
+}
+
+// Lock takes the lock for writing.
+func (l *fairRWMutex) Lock() {
+	l.acquire(true)
+}
+
+// Unlock releases the write lock.
+func (l *fairRWMutex) Unlock() {
+	l.mu.Lock()
+	l.writer = false
+	l.release()
+	l.mu.Unlock()
+}
+
+// RLock takes the lock for reading.
+func (l *fairRWMutex) RLock() {
+	l.acquire(false)
+}
+
+// RUnlock releases a read lock.
+func (l *fairRWMutex) RUnlock() {
+	l.mu.Lock()
+	l.readers--
+	if l.readers == 0 {
+		l.release()
+	}
+	l.mu.Unlock()
+}
+
+// acquire takes the lock for writing or reading, queueing until it is free
+// or handed over.
+func (l *fairRWMutex) acquire(write bool) {
+	var w *fairWaiter
+	l.mu.Lock()
+	for {
+		if l.free(write) && (w != nil || !l.starving()) {
+			l.take(write)
+			l.mu.Unlock()
+			return
+		}
+		if w == nil {
+			w = &fairWaiter{write: write, since: time.Now()}
+			w.ready = make(chan struct{})
+			l.queue = append(l.queue, w)
+		} else {
+			// A woken caller that lost the race keeps its place.
+			w.ready = make(chan struct{})
+			l.queue = append([]*fairWaiter{w}, l.queue...)
+		}
+		l.mu.Unlock()
+		<-w.ready
+		l.mu.Lock()
+		if w.granted {
+			l.mu.Unlock()
+			return
+		}
+	}
+}
+
+// free reports whether the lock can be taken for writing or reading as it
+// is held now. The caller must hold mu.
+func (l *fairRWMutex) free(write bool) bool {
+	if write {
+		return !l.writer && l.readers == 0
+	}
+	return !l.writer
+}
+
+// take marks the lock as held for writing or reading. The caller must hold
+// mu.
+func (l *fairRWMutex) take(write bool) {
+	if write {
+		l.writer = true
+	} else {
+		l.readers++
+	}
+}
+
+// starving reports whether the head of the queue has waited longer than
+// fairWait. The caller must hold mu.
+func (l *fairRWMutex) starving() bool {
+	return len(l.queue) > 0 && time.Since(l.queue[0].since) > fairWait
+}
+
+// release passes on the lock once it is free: it hands it over in queue
+// order if the head of the queue is starving, and otherwise wakes the head
+// to compete for it. The caller must hold mu.
+func (l *fairRWMutex) release() {
+	if !l.starving() {
+		if len(l.queue) > 0 && l.free(l.queue[0].write) {
+			close(l.pop().ready)
+		}
+		return
+	}
+	for len(l.queue) > 0 && l.free(l.queue[0].write) {
+		w := l.pop()
+		l.take(w.write)
+		w.granted = true
+		close(w.ready)
+		if w.write {
+			return
+		}
+	}
+}
+
+// pop removes and returns the head of the queue. The caller must hold mu.
+func (l *fairRWMutex) pop() *fairWaiter {
+	w := l.queue[0]
+	l.queue[0] = nil
+	l.queue = l.queue[1:]
+	return w
+}
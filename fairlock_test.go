@@ -0,0 +1,110 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queued returns the number of callers waiting on l.
+func (l *fairRWMutex) queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queue)
+}
+
+// TestFairRWMutexOrder queues writers and readers behind a write lock held
+// for longer than fairWait and checks that they are granted in arrival
+// order: r4, which arrives while
+// the lock is held, never overtakes w3, and the readers between two writers
+// share the lock, in either order, before the writer behind them.
+// sync.RWMutex would hand the lock to all three readers first.
+func TestFairRWMutexOrder(t *testing.T) {
+	var l fairRWMutex
+	l.Lock()
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	record := func(name string) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+	for i, write := range []bool{true, false, false, true, false} {
+		name := fmt.Sprintf("r%d", i)
+		if write {
+			name = fmt.Sprintf("w%d", i)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if write {
+				l.Lock()
+				record(name)
+				l.Unlock()
+				return
+			}
+			l.RLock()
+			record(name)
+			l.RUnlock()
+		}()
+		for l.queued() != i+1 {
+			runtime.Gosched()
+		}
+	}
+
+	// Once the queue has waited longer than fairWait, it is served in order.
+	time.Sleep(2 * fairWait)
+	l.Unlock()
+	wg.Wait()
+
+	got := strings.Join(order, " ")
+	if got != "w0 r1 r2 w3 r4" && got != "w0 r2 r1 w3 r4" {
+		t.Fatalf("grant order %q, want w0, then r1 and r2, then w3, then r4", got)
+	}
+}
+
+// TestFairLocking runs concurrent writers and readers against a cache with
+// FairLocking set and checks that it stays consistent.
+func TestFairLocking(t *testing.T) {
+	m, err := NewWithOptions(WithShardCap(2), WithNodeCap(50), WithFairLocking(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, ok := m.pool[0].mut.(*fairRWMutex); !ok {
+		t.Fatalf("shard lock is a %T, want *fairRWMutex", m.pool[0].mut)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("key%d", (g*31+i)%100)
+				if i%3 == 0 {
+					m.Set(key, i, 1)
+				} else {
+					m.Get(key)
+					m.Len()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"testing"
+	"time"
+)
+
+// FuzzCacheOps decodes data into a sequence of Set, Get, Remove, SetTTL and
+// Rehash calls on a small cache, and checks with Verify after every step that
+// the shards' lists, heaps and accounting still agree. The first byte picks
+// the eviction policy; every following triple of bytes is an operation, a key
+// and a size. The keys and sizes are few and small so that entries collide,
+// get replaced and get evicted for both count and cost.
+func FuzzCacheOps(f *testing.F) {
+	f.Add([]byte{0, 0, 1, 4, 1, 1, 0, 2, 1, 0, 3, 2, 9})
+	f.Add([]byte{4, 0, 1, 8, 0, 2, 8, 0, 3, 8, 4, 7, 0, 1, 1, 0})
+	f.Add([]byte{2, 3, 5, 20, 0, 5, 30, 1, 5, 0, 2, 5, 0, 4, 9, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		m := New(&Config{ShardCap: 4, NodeCap: 4, MaxCost: 64, Policy: Policy(data[0] % 5)})
+		defer m.Close()
+
+		for i := 1; i+2 < len(data); i += 3 {
+			op, key, size := data[i]%5, fmt.Sprintf("key%d", data[i+1]%16), uint64(data[i+2]%40)
+			switch op {
+			case 0:
+				m.Set(key, i, size)
+			case 1:
+				m.Get(key)
+			case 2:
+				m.Remove(key)
+			case 3:
+				m.SetTTL(key, i, size, time.Duration(data[i+2]%3)*time.Hour)
+			case 4:
+				seed := data[i+2]
+				m.Rehash(func(s string) uint64 {
+					h := fnv.New64a()
+					h.Write([]byte{seed})
+					h.Write([]byte(s))
+					return h.Sum64()
+				})
+			}
+			if err := m.Verify(); err != nil {
+				t.Fatalf("after op %d (%d on %s, size %d): %v", i/3, op, key, size, err)
+			}
+		}
+	})
+}
+
+// FuzzEvictionHeap decodes data into a sequence of pushes, removals of
+// arbitrary nodes and pops on an EvictionHeap, and checks after every step
+// that the heap property holds and that every node's heap index matches its
+// position. Each byte is one operation; its low bits pick the operation and
+// the rest pick the node's ranking or the node to remove.
+func FuzzEvictionHeap(f *testing.F) {
+	f.Add([]byte{0, 4, 8, 12, 1, 2, 16, 5})
+	f.Add([]byte{0, 0, 0, 0, 5, 5, 2, 2, 2})
+	f.Add([]byte{252, 128, 64, 32, 9, 13, 2, 6, 1})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var eh EvictionHeap
+		var seq uint64
+		for i, b := range data {
+			switch b % 4 {
+			case 0, 3:
+				seq++
+				heap.Push(&eh, &Nodes{
+					Key:       fmt.Sprintf("node%d", seq),
+					weight:    uint64(b>>2) % 4,
+					freq:      uint64(b>>4) % 3,
+					lastUsed:  int64(b >> 5),
+					pinned:    b&0x80 != 0 && b%4 == 3,
+					seq:       seq,
+					heapIndex: -1,
+				})
+			case 1:
+				if eh.Len() == 0 {
+					continue
+				}
+				node := eh[int(b>>2)%eh.Len()]
+				eh.RemoveNode(node)
+				if node.heapIndex != -1 {
+					t.Fatalf("op %d: removed node has heap index %d, want -1", i, node.heapIndex)
+				}
+			case 2:
+				if eh.Len() == 0 {
+					continue
+				}
+				min := eh[0]
+				if got := heap.Pop(&eh).(*Nodes); got != min {
+					t.Fatalf("op %d: Pop returned %s, want the minimum %s", i, got.Key, min.Key)
+				}
+			}
+
+			if err := eh.verify(); err != nil {
+				t.Fatalf("op %d: %v", i, err)
+			}
+			for j, node := range eh {
+				if node.heapIndex != j {
+					t.Fatalf("op %d: node %s at %d has heap index %d", i, node.Key, j, node.heapIndex)
+				}
+			}
+		}
+	})
+}
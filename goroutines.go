@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// spawn runs fn on a new goroutine supervised by the manager, unless that would
+// exceed the MaxGoroutines limit, in which case fn is not run and spawn returns
+// false. Every goroutine the package starts goes through spawn, so that the
+// number running never exceeds the limit. Nothing is spawned once the
+// manager's done channel is closed.
+func (m *CacheManager) spawn(fn func()) bool {
+	m.spawnMut.Lock()
+	defer m.spawnMut.Unlock()
+
+	select {
+	case <-m.done:
+		return false
+	default:
+	}
+
+	for {
+		n := m.goroutines.Load()
+		if m.maxGoroutines > 0 && int(n) >= m.maxGoroutines {
+			return false
+		}
+		if m.goroutines.CompareAndSwap(n, n+1) {
+			break
+		}
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.goroutines.Add(-1)
+		fn()
+	}()
+	return true
+}
+
+// startCleaner starts the cleaner of a shard. If no goroutine is available
+// for it, the shard is swept by the shared cleaner instead.
+func (m *CacheManager) startCleaner(shard *NodeShards) {
+	shard.ownCleaner = m.spawn(shard.startCleaner)
+}
+
+// sharedCleaner sweeps the shards that have no cleaner of their own, every
+// interval, until the manager's done channel is closed.
+func (m *CacheManager) sharedCleaner(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.poolMut.RLock()
+			var shards []*NodeShards
+			for _, shard := range m.pool {
+				if !shard.ownCleaner {
+					shards = append(shards, shard)
+				}
+			}
+			m.poolMut.RUnlock()
+
+			for _, shard := range shards {
+				shard.cleanExpired()
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
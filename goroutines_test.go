@@ -0,0 +1,45 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMaxGoroutines caps the goroutines of a cache with more shards than
+// the cap allows cleaners for, and checks that the cap holds, with the
+// shared cleaner and the stats report started first, and that Close stops
+// every goroutine.
+func TestMaxGoroutines(t *testing.T) {
+	m := New(&Config{
+		ShardCap:      8,
+		NodeCap:       10,
+		EnableCleaner: true,
+		MaxGoroutines: 3,
+		StatsInterval: time.Hour,
+		OnStats:       func(Stats) {},
+	})
+
+	if n := m.goroutines.Load(); n != 3 {
+		t.Fatalf("%d goroutines running, want the cap of 3", n)
+	}
+	own := 0
+	for _, shard := range m.pool {
+		if shard.ownCleaner {
+			own++
+		}
+	}
+	// The shared cleaner and the stats report take two of the three.
+	if own != 1 {
+		t.Fatalf("%d shards have their own cleaner, want 1", own)
+	}
+
+	m.Close()
+	if n := m.goroutines.Load(); n != 0 {
+		t.Fatalf("%d goroutines running after Close, want 0", n)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// EntryInfo describes an entry of the cache, as returned by Inspect.
+type EntryInfo struct {
+	// Key is the key the entry is stored under.
+	Key string
+
+	// Shard is the index of the shard holding the entry.
+	Shard int
+
+	// Size is the size of the entry, as counted against MaxCost.
+	Size uint64
+
+	// Weight is the eviction weight given by SetWeighted, and Weighted
+	// reports whether the entry was given one.
+	Weight   uint64
+	Weighted bool
+
+	// CreatedAt is when the entry was first stored, and LastUsed when it
+	// was last read or written. Both have a resolution of one second.
+	CreatedAt time.Time
+	LastUsed  time.Time
+
+	// ExpiresAt is when the entry expires, or the zero time if it never
+	// does. TTL is the time-to-live it was last given, which SlidingTTL
+	// refreshes it by.
+	ExpiresAt time.Time
+	TTL       time.Duration
+
+	// Pinned reports whether the entry is pinned by Pin, and ReadOnly
+	// whether it was stored by SetReadOnly.
+	Pinned   bool
+	ReadOnly bool
+
+	// Evictable reports whether capacity or cost pressure can evict the
+	// entry: it is neither pinned nor a pure TTL entry stored by
+	// SetTTLNoLRU.
+	Evictable bool
+}
+
+// Inspect returns the metadata of the entry stored under key, and whether
+// the key is present and unexpired, without reading or promoting the entry.
+// It is meant for operators finding out why an entry survives or leaves the
+// cache; the answer may be stale as soon as Inspect returns. Writes still
+// buffered by WriteCoalesceWindow are not reported until they are applied.
+func (m *CacheManager) Inspect(key string) (EntryInfo, bool) {
+	shard := m.rlockKey(key)
+	defer m.runlockKey(shard)
+
+	node, exists := shard.pool[key]
+	if !exists || shard.stale(node, time.Now().Unix()) {
+		return EntryInfo{}, false
+	}
+
+	info := EntryInfo{
+		Key:       key,
+		Shard:     m.shardIndex(key),
+		Size:      node.nodeSize,
+		Weighted:  node.weighted,
+		CreatedAt: time.Unix(node.createdAt, 0),
+		LastUsed:  time.Unix(node.lastUsed, 0),
+		TTL:       node.ttl,
+		Pinned:    node.pinned,
+		ReadOnly:  node.readOnly,
+		Evictable: !node.pinned && !node.noLRU,
+	}
+	if node.weighted {
+		info.Weight = node.weight
+	}
+	if node.expiredAt > 0 {
+		info.ExpiresAt = time.Unix(node.expiredAt, 0)
+	}
+	return info, true
+}
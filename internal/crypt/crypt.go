@@ -10,17 +10,16 @@ import (
 )
 
 // JCH represents a Jump Consistent Hashing structure.
-// It manages the number of buckets and a cache for hashed keys.
+// It holds the number of buckets keys are mapped to. A JCH keeps no state
+// per key, so it is safe for concurrent use.
 type JCH struct {
-	numBuckets int               // Number of buckets for hashing
-	hashCache  map[string]uint64 // Cache to store computed hash values for keys
+	numBuckets int // Number of buckets for hashing
 }
 
 // NewjCH creates a new instance of JCH with the specified number of buckets.
 func NewjCH(numBuckets int) *JCH {
 	return &JCH{
 		numBuckets: numBuckets,
-		hashCache:  make(map[string]uint64),
 	}
 }
 
@@ -33,15 +32,27 @@ func (j *JCH) Hash(key string) uint64 {
 }
 
 // GetBucket retrieves the bucket index for a given key.
-// It checks the hash cache first; if the key is not cached, it computes the hash,
-// determines the bucket index, caches it, and returns the index.
+// It hashes the key and determines the bucket index with JumpHash. Results
+// are not cached: hashing a key is cheaper than a synchronized lookup, and a
+// cache keyed by every distinct key would grow without bound.
 func (j *JCH) GetBucket(key string) uint64 {
-	if bucket, exists := j.hashCache[key]; exists {
-		return bucket
+	return uint64(JumpHash(j.Hash(key), j.numBuckets))
+}
+
+// JumpHash maps a 64-bit key to a bucket in [0, numBuckets) using the jump
+// consistent hash of Lamping and Veach. When numBuckets grows from N to N+1,
+// only about 1/(N+1) of the keys move, and all of them move to the new bucket.
+// It returns -1 if numBuckets is not positive.
+func JumpHash(key uint64, numBuckets int) int32 {
+	if numBuckets <= 0 {
+		return -1
 	}
 
-	h := j.Hash(key)
-	bucket := h % uint64(j.numBuckets)
-	j.hashCache[key] = bucket
-	return bucket
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int32(b)
 }
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package crypt
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+)
+
+// TestJumpHashKeyMovement grows the number of buckets from n to n+1 and
+// checks that about 1/(n+1) of the keys move, all of them to the new bucket.
+func TestJumpHashKeyMovement(t *testing.T) {
+	const keys = 100_000
+	j := NewjCH(1)
+	hashes := make([]uint64, keys)
+	for i := range hashes {
+		hashes[i] = j.Hash(fmt.Sprintf("key:%d", i))
+	}
+
+	for _, n := range []int{1, 2, 3, 7, 15, 16, 31, 63, 100} {
+		moved := 0
+		for _, h := range hashes {
+			before, after := JumpHash(h, n), JumpHash(h, n+1)
+			if before == after {
+				continue
+			}
+			if after != int32(n) {
+				t.Fatalf("%d to %d buckets: key moved from %d to %d, want the new bucket", n, n+1, before, after)
+			}
+			moved++
+		}
+
+		want := float64(keys) / float64(n+1)
+		if math.Abs(float64(moved)-want) > 0.1*want {
+			t.Errorf("%d to %d buckets: %d keys moved, want about %.0f", n, n+1, moved, want)
+		}
+	}
+}
+
+// TestGetBucketConcurrent calls GetBucket on one JCH from many goroutines,
+// which the race detector flags if it shares unsynchronized state, and
+// checks that every goroutine gets the same bucket for every key.
+func TestGetBucketConcurrent(t *testing.T) {
+	j := NewjCH(16)
+	want := make([]uint64, 1000)
+	for i := range want {
+		want[i] = j.GetBucket(fmt.Sprintf("key:%d", i))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 64; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := range want {
+				k := (i + g*17) % len(want)
+				if got := j.GetBucket(fmt.Sprintf("key:%d", k)); got != want[k] {
+					errs <- fmt.Errorf("GetBucket(key:%d) = %d, want %d", k, got, want[k])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "reflect"
+
+// GetInto retrieves the value stored under key and stores it in the value dst
+// points to, reporting whether it succeeded. dst must be a non-nil pointer. The
+// stored value is assigned if its type is assignable to the element type of dst;
+// a stored pointer is dereferenced first if that makes it assignable. When value
+// encoding is enabled the value is decoded before being assigned. GetInto
+// returns false, leaving dst untouched, if the key is missing or the types do
+// not match. A successful lookup promotes the entry in the LRU as with Get.
+func (m *CacheManager) GetInto(key string, dst interface{}) bool {
+	target := reflect.ValueOf(dst)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return false
+	}
+	elem := target.Elem()
+
+	val, ok := m.GetOK(key)
+	if !ok || val == nil {
+		return false
+	}
+
+	src := reflect.ValueOf(val)
+	if !src.Type().AssignableTo(elem.Type()) {
+		if src.Kind() != reflect.Ptr || src.IsNil() || !src.Elem().Type().AssignableTo(elem.Type()) {
+			return false
+		}
+		src = src.Elem()
+	}
+
+	elem.Set(src)
+	return true
+}
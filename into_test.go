@@ -0,0 +1,37 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "testing"
+
+// TestGetInto checks that GetInto assigns stored values and dereferenced
+// pointers to dst, and leaves dst untouched on a miss or a type mismatch.
+func TestGetInto(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.Set("int", 42, 1)
+	m.Set("ptr", &point{1, 2}, 1)
+
+	var n int
+	if !m.GetInto("int", &n) || n != 42 {
+		t.Errorf("GetInto(int) gave %d, want 42", n)
+	}
+	var p point
+	if !m.GetInto("ptr", &p) || p != (point{1, 2}) {
+		t.Errorf("GetInto(ptr) gave %v, want the dereferenced point{1, 2}", p)
+	}
+
+	s := "untouched"
+	if m.GetInto("int", &s) || s != "untouched" {
+		t.Errorf("GetInto(int) into a string succeeded or changed it to %q", s)
+	}
+	if m.GetInto("missing", &n) || n != 42 {
+		t.Errorf("GetInto(missing) succeeded or changed dst to %d", n)
+	}
+	if m.GetInto("int", n) {
+		t.Error("GetInto into a non-pointer succeeded")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"time"
+)
+
+// lazyValue holds a deferred computation stored by SetLazy.
+// The computation runs at most once, no matter how many readers observe it.
+type lazyValue struct {
+	once    sync.Once
+	compute func() (interface{}, uint64)
+	value   interface{}
+	size    uint64
+}
+
+// resolve runs the deferred computation on first use and returns its result.
+// Concurrent callers block until the single computation has finished.
+func (lv *lazyValue) resolve() (interface{}, uint64) {
+	lv.once.Do(func() {
+		lv.value, lv.size = lv.compute()
+		lv.compute = nil
+	})
+	return lv.value, lv.size
+}
+
+// SetLazy stores a computation for the given key instead of a value.
+// The first Get of the key runs compute, replaces the stored computation with
+// its result and size, and returns the result. Concurrent first readers share a
+// single run of compute. A ttl of zero or less means the entry never expires.
+func (m *CacheManager) SetLazy(key string, compute func() (interface{}, uint64), ttl time.Duration) {
+	m.SetTTL(key, &lazyValue{compute: compute}, 0, ttl)
+}
+
+// resolveLazy evaluates a lazy value read from node and, if the node still
+// holds it, replaces the stored computation with the computed value and size.
+// The computation runs outside the shard lock so it may use the cache itself,
+// and poolMut is read-locked with the shard so that dynamic sharding never
+// sees the shard mid-update.
+func (m *CacheManager) resolveLazy(shard *NodeShards, node *Nodes, lv *lazyValue) interface{} {
+	val, size := lv.resolve()
+
+	m.poolMut.RLock()
+	shard.lock()
+	if shard.pool[node.Key] == node && node.Value == lv {
+		if shard.fits(node, size) {
+			shard.shrink(node.nodeSize)
+			node.Value = val
+			node.nodeSize = size
+			shard.grow(size)
+		} else {
+			shard.deleteNode(node, removalEvicted)
+		}
+	}
+	m.unlockKey(shard)
+	m.reserve(0)
+
+	return val
+}
+
+// resolveValue returns val as read from node, evaluating it first if it is
+// a lazy value stored by SetLazy and decoding it if it was stored encoded.
+func (m *CacheManager) resolveValue(shard *NodeShards, node *Nodes, val interface{}) interface{} {
+	if lv, ok := val.(*lazyValue); ok {
+		return m.resolveLazy(shard, node, lv)
+	}
+	return m.decode(val)
+}
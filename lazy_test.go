@@ -0,0 +1,49 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSetLazyComputesOnFirstGet checks that a lazy value is computed by the
+// first Get rather than by SetLazy, once for concurrent readers, and that the
+// stored entry then takes the computed size.
+func TestSetLazyComputesOnFirstGet(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+
+	var runs atomic.Int32
+	m.SetLazy("key", func() (interface{}, uint64) {
+		runs.Add(1)
+		return "value", 5
+	}, time.Hour)
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("computation ran %d times before any Get, want 0", n)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v := m.Get("key"); v != "value" {
+				t.Errorf("Get(key) = %v, want value", v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("computation ran %d times, want 1", n)
+	}
+	if c := m.Cost(); c != 5 {
+		t.Fatalf("Cost() = %d after the computation, want 5", c)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"time"
+)
+
+// Loader loads the value of a key that is missing from the cache, returning
+// the value and its size as it would be passed to SetTTL.
+type Loader func(key string) (value interface{}, size uint64, err error)
+
+// SingleFlight runs fn once for concurrent callers sharing a key: callers
+// arriving while a call for their key is in flight wait for it and receive its
+// result. *singleflight.Group from golang.org/x/sync implements it.
+type SingleFlight interface {
+	Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool)
+}
+
+// GetLoad returns the value stored under key, loading it on a miss. A missing
+// key is loaded with load and stored with SetTTL for ttl, and the loaded
+// value is returned. Concurrent GetLoads of the same missing key are
+// deduplicated through Config.SingleFlight, or the cache's own equivalent, so
+// load runs once and every caller receives its result. A load error is
+// returned to every waiting caller and nothing is stored. load runs without
+// any lock held and may use the cache.
+func (m *CacheManager) GetLoad(key string, ttl time.Duration, load Loader) (interface{}, error) {
+	if val, ok := m.GetOK(key); ok {
+		return val, nil
+	}
+
+	val, err, _ := m.flight.Do(key, func() (interface{}, error) {
+		if val, ok := m.GetOK(key); ok {
+			return val, nil
+		}
+		val, size, err := load(key)
+		if err != nil {
+			return nil, err
+		}
+		m.SetTTL(key, val, size, ttl)
+		return val, nil
+	})
+	return val, err
+}
+
+// GetOrCompute returns the value stored under key, computing it with fn on a
+// miss. It is GetLoad for a computation that does not need the key: fn runs
+// at most once for concurrent callers of the same missing key, a successful
+// result is stored with the given size and ttl, and an error is returned to
+// every waiting caller without being cached.
+func (m *CacheManager) GetOrCompute(key string, size uint64, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	return m.GetLoad(key, ttl, func(string) (interface{}, uint64, error) {
+		val, err := fn()
+		return val, size, err
+	})
+}
+
+// loadGroup is the built-in SingleFlight used when none is configured.
+type loadGroup struct {
+	mut   sync.Mutex
+	calls map[string]*loadCall
+}
+
+// loadCall is a call in flight, or completed, within a loadGroup.
+type loadCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// Do implements SingleFlight.
+func (g *loadGroup) Do(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	g.mut.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*loadCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mut.Unlock()
+		call.wg.Wait()
+		return call.val, call.err, true
+	}
+	call := &loadCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mut.Unlock()
+
+	defer func() {
+		g.mut.Lock()
+		delete(g.calls, key)
+		g.mut.Unlock()
+		call.wg.Done()
+	}()
+	call.val, call.err = fn()
+	return call.val, call.err, false
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGetOrComputeRunsOnce requests the same missing key from 1000
+// goroutines at once and checks that the computation ran once and that every
+// caller received its result.
+func TestGetOrComputeRunsOnce(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+
+	var runs atomic.Int32
+	compute := func() (interface{}, error) {
+		runs.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return "value", nil
+	}
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	var wrong atomic.Int32
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if v, err := m.GetOrCompute("hot", 1, time.Hour, compute); err != nil || v != "value" {
+				wrong.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("computation ran %d times, want 1", n)
+	}
+	if n := wrong.Load(); n != 0 {
+		t.Fatalf("%d callers got a wrong result", n)
+	}
+	if v, ok := m.GetOK("hot"); !ok || v != "value" {
+		t.Fatalf("GetOK(hot) = %v, %v; want value, true", v, ok)
+	}
+}
+
+// TestGetOrComputeDoesNotCacheErrors checks that a failed computation is
+// returned and not stored, so that the next call computes again.
+func TestGetOrComputeDoesNotCacheErrors(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+
+	errFailed := errors.New("failed")
+	if _, err := m.GetOrCompute("key", 1, time.Hour, func() (interface{}, error) {
+		return nil, errFailed
+	}); !errors.Is(err, errFailed) {
+		t.Fatalf("GetOrCompute = %v, want errFailed", err)
+	}
+	if _, ok := m.GetOK("key"); ok {
+		t.Fatal("failed computation was stored")
+	}
+
+	v, err := m.GetOrCompute("key", 1, time.Hour, func() (interface{}, error) {
+		return 2, nil
+	})
+	if err != nil || v != 2 {
+		t.Fatalf("GetOrCompute = %v, %v after a failure; want 2, nil", v, err)
+	}
+}
+
+// countingFlight is a SingleFlight that runs every call and counts them.
+type countingFlight struct{ calls atomic.Int32 }
+
+func (f *countingFlight) Do(_ string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	f.calls.Add(1)
+	v, err := fn()
+	return v, err, false
+}
+
+// TestGetLoadSingleFlight checks that GetLoad routes misses through the
+// configured SingleFlight, passes the key to the loader and stores the result
+// for ttl, and serves hits without loading.
+func TestGetLoadSingleFlight(t *testing.T) {
+	flight := &countingFlight{}
+	m, err := NewWithOptions(WithShardCap(1), WithNodeCap(10), WithSingleFlight(flight))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	load := func(key string) (interface{}, uint64, error) {
+		return "loaded " + key, 1, nil
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := m.GetLoad("key", time.Minute, load); err != nil || v != "loaded key" {
+			t.Fatalf("GetLoad(key) = %v, %v; want loaded key, nil", v, err)
+		}
+	}
+	if n := flight.calls.Load(); n != 1 {
+		t.Fatalf("SingleFlight called %d times, want once for the miss", n)
+	}
+	if ttl, ok := m.TTL("key"); !ok || ttl > time.Minute {
+		t.Fatalf("TTL(key) = %v, %v; want the minute it was loaded with", ttl, ok)
+	}
+}
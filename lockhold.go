@@ -0,0 +1,60 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// lock takes the shard's write lock and, when lock holds are tracked, notes
+// when it was taken. It is released with unlock.
+func (ns *NodeShards) lock() {
+	ns.mut.Lock()
+	if ns.trackHold {
+		ns.lockedAt = time.Now()
+	}
+}
+
+// recordHold folds the duration of the current write lock hold into maxHold.
+// The caller must hold the write lock taken by lock.
+func (ns *NodeShards) recordHold() {
+	if !ns.trackHold || ns.lockedAt.IsZero() {
+		return
+	}
+	held := int64(time.Since(ns.lockedAt))
+	ns.lockedAt = time.Time{}
+	for {
+		max := ns.maxHold.Load()
+		if held <= max || ns.maxHold.CompareAndSwap(max, held) {
+			return
+		}
+	}
+}
+
+// MaxLockHold returns the longest time any shard's write lock has been held
+// since the cache was created or since the last ResetMaxLockHold. Read locks
+// are not measured. It returns zero unless Config.TrackLockHold is set.
+func (m *CacheManager) MaxLockHold() time.Duration {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	var max int64
+	for _, shard := range m.pool {
+		if held := shard.maxHold.Load(); held > max {
+			max = held
+		}
+	}
+	return time.Duration(max)
+}
+
+// ResetMaxLockHold clears the longest lock hold recorded by every shard, so
+// that MaxLockHold reports only holds that end after the reset.
+func (m *CacheManager) ResetMaxLockHold() {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	for _, shard := range m.pool {
+		shard.maxHold.Store(0)
+	}
+}
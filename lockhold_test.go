@@ -0,0 +1,40 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMaxLockHold holds a shard's write lock for a known time and checks that
+// MaxLockHold reports at least that long, that ResetMaxLockHold clears it,
+// and that nothing is recorded unless TrackLockHold is set.
+func TestMaxLockHold(t *testing.T) {
+	for _, track := range []bool{true, false} {
+		m := New(&Config{ShardCap: 2, NodeCap: 10, TrackLockHold: track})
+		m.Set("key", 1, 1)
+
+		shard := m.pool[1]
+		shard.lock()
+		time.Sleep(20 * time.Millisecond)
+		shard.unlock()
+
+		held := m.MaxLockHold()
+		if track && held < 20*time.Millisecond {
+			t.Errorf("MaxLockHold() = %v, want at least the 20ms hold", held)
+		}
+		if !track && held != 0 {
+			t.Errorf("MaxLockHold() = %v without TrackLockHold, want 0", held)
+		}
+
+		m.ResetMaxLockHold()
+		if held := m.MaxLockHold(); held >= 20*time.Millisecond {
+			t.Errorf("MaxLockHold() = %v after ResetMaxLockHold, want the hold forgotten", held)
+		}
+		m.Close()
+	}
+}
@@ -6,8 +6,11 @@
 package cerebru
 
 import (
-	"container/heap"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bluespada/cerebru/internal/crypt"
 )
@@ -21,6 +24,124 @@ type CacheManager struct {
 	poolMut                                      sync.RWMutex
 	jch                                          *crypt.JCH
 	maxCost                                      uint64
+
+	// draining makes writes no-ops while reads keep being served.
+	draining atomic.Bool
+
+	// onShardChange is notified when dynamic sharding adds or removes shards.
+	onShardChange func(delta int, total int)
+
+	// releaseMut guards released, a channel closed and replaced whenever
+	// entries leave the cache while goroutines are waiting for capacity.
+	releaseMut     sync.Mutex
+	released       chan struct{}
+	releaseWaiters atomic.Int32
+
+	// stats holds the cumulative counters reported by Stats.
+	stats *cacheStats
+
+	// closeOnEvict closes values implementing io.Closer when they leave the cache.
+	closeOnEvict bool
+
+	// onEvict and onExpire are called for entries evicted and expired.
+	onEvict  func(key string, value interface{})
+	onExpire func(key string, value interface{})
+
+	// hysteresis is the fraction of capacity freed when a shard overflows.
+	hysteresis float64
+
+	// minRebalance is the minimum time between two dynamic scalings, and
+	// lastRebalance the time of the last one, in Unix nanoseconds.
+	minRebalance  time.Duration
+	lastRebalance atomic.Int64
+
+	// minPool is the number of shards dynamic sharding never shrinks the
+	// pool below: minShards, or more once EnsureShards asked for them. It
+	// is guarded by poolMut.
+	minPool int
+
+	// checkpointPath is the file Checkpoint writes to; empty if unset.
+	checkpointPath string
+
+	// policy is the built-in eviction policy given to every shard, unless
+	// customPolicy is set to make the shards' policies instead.
+	policy       Policy
+	customPolicy func() EvictionPolicy
+
+	// done is closed to stop the manager's background goroutines.
+	done chan struct{}
+
+	// closed is set once Close has stopped the manager; closeOnce guards it.
+	closed    atomic.Bool
+	closeOnce sync.Once
+
+	// strictCost makes writes charge their cost against maxCost before
+	// inserting, so that the cache never exceeds it.
+	strictCost bool
+
+	// codec encodes stored values; nil unless value encoding is enabled.
+	codec Codec
+
+	// cleanerBudget caps the expired nodes removed per shard per sweep.
+	cleanerBudget int
+
+	// cleanerYield makes cleaners skip sweeps of shards under write pressure.
+	cleanerYield bool
+
+	// ttlRules are the default TTLs by key prefix applied by Set.
+	ttlRules []TTLRule
+
+	// maxGoroutines caps the background goroutines started through spawn;
+	// goroutines counts those running and wg waits for them. spawnMut
+	// keeps goroutines from being added to wg once done is closed.
+	maxGoroutines int
+	goroutines    atomic.Int32
+	wg            sync.WaitGroup
+	spawnMut      sync.Mutex
+
+	// shardFunc, if set, overrides hashing when choosing a key's shard.
+	shardFunc func(key string, n int) int
+
+	// hasher, if set by Rehash, replaces the internal key hash.
+	hasher atomic.Pointer[func(string) uint64]
+
+	// compressMinSize and shouldCompress decide which values are compressed.
+	compressMinSize uint64
+	shouldCompress  func(key string, value interface{}, size uint64) bool
+
+	// slidingTTL extends entries on read; ttlJitter spreads their
+	// expiries by up to that fraction of their lifetime.
+	slidingTTL bool
+	ttlJitter  float64
+
+	// rng seeds the random source of each shard. It is only used with
+	// poolMut locked.
+	rng *rand.Rand
+
+	// requireTTL makes Set panic for keys no TTL rule covers.
+	requireTTL bool
+
+	// trackLockHold makes shards record their longest write lock hold.
+	trackLockHold bool
+
+	// fairLocking gives every shard a fairRWMutex.
+	fairLocking bool
+
+	// flight deduplicates concurrent GetLoad loads of the same key.
+	flight SingleFlight
+
+	// coalescer buffers writes; nil unless write coalescing is enabled.
+	coalescer *coalescer
+
+	// accessLog records recent operations; nil unless enabled.
+	accessLog *accessLog
+
+	// cost is the total cost of the entries held by all shards, checked
+	// against maxCost before writes.
+	cost atomic.Uint64
+
+	// epoch is the cache generation; bumping it invalidates older entries.
+	epoch atomic.Uint64
 }
 
 // addShard creates a new NodeShards instance and adds it to the pool.
@@ -29,128 +150,320 @@ func (m *CacheManager) addShard() {
 	// m.poolMut.Lock()
 	// defer m.poolMut.Unlock()
 	shard := &NodeShards{
-		pool:         make(map[string]*Nodes),
-		head:         &Nodes{},
-		tail:         &Nodes{},
-		capacity:     m.nodeCap,
-		cleanerStop:  make(chan struct{}),
-		evictionHeap: &EvictionHeap{},
-		nodeIndex:    map[*Nodes]int{},
-		mut:          sync.RWMutex{},
+		pool:          make(map[string]*Nodes),
+		head:          &Nodes{},
+		tail:          &Nodes{},
+		capacity:      m.nodeCap,
+		maxCost:       m.maxCost,
+		cleanerStop:   make(chan struct{}),
+		cleanerBudget: m.cleanerBudget,
+		cleanerYield:  m.cleanerYield,
+		mut:           newShardMutex(m.fairLocking),
+		newEvictor:    m.evictionPolicy,
+		release:       m.signalRelease,
+		epoch:         &m.epoch,
+		stats:         m.stats,
+		cost:          &m.cost,
+		poolMut:       &m.poolMut,
+		strict:        m.strictCost,
+		trackHold:     m.trackLockHold,
+		hysteresis:    m.hysteresis,
+	}
+	if m.ttlJitter > 0 {
+		shard.jitter = m.ttlJitter
+		shard.rng = rand.New(rand.NewSource(m.rng.Int63()))
+	}
+	if m.closeOnEvict || m.onEvict != nil || m.onExpire != nil {
+		shard.dispose = m.dispose
 	}
 	shard.head.next = shard.tail
 	shard.tail.prev = shard.head
-	heap.Init(shard.evictionHeap)
+	shard.setEvictor()
 
 	if m.enableAutoCleaner {
-		go shard.startCleaner()
+		m.startCleaner(shard)
 	}
 
 	m.pool = append(m.pool, shard)
 }
 
-// findLeastLoadedShard returns the shard with the least number of nodes.
-func (m *CacheManager) findLeastLoadedShard() *NodeShards {
-	var target *NodeShards
-	minLoad := int(^uint(0) >> 1)
-
-	for _, shard := range m.pool {
-		count := len(shard.pool)
-		if count < minLoad {
-			minLoad = count
-			target = shard
+// shardIndex returns the index in the pool of the shard that key belongs to.
+// It uses the configured ShardFunc if any, wrapping an out of range result
+// into the pool. Otherwise the key's hash tag is hashed, by the hasher
+// installed with Rehash or the internal FNV-1a one, and the hash is mapped to
+// a shard with jump consistent hashing, so that adding a shard only moves
+// about 1/(N+1) of the keys.
+func (m *CacheManager) shardIndex(key string) int {
+	n := len(m.pool)
+	if m.shardFunc != nil {
+		i := m.shardFunc(key, n) % n
+		if i < 0 {
+			i += n
 		}
+		return i
+	}
+	if h := m.hasher.Load(); h != nil {
+		return int(crypt.JumpHash((*h)(hashTag(key)), n))
+	}
+	return int(crypt.JumpHash(m.jch.Hash(hashTag(key)), n))
+}
+
+// shardFor returns the shard that key belongs to. Every operation on a single
+// key resolves its shard through shardFor, so that reads, writes and removals
+// of a key always agree on where it lives. The caller must hold poolMut, for
+// reading at least, for as long as it uses the shard; see lockKey.
+func (m *CacheManager) shardFor(key string) *NodeShards {
+	return m.pool[m.shardIndex(key)]
+}
+
+// lockKey write-locks the shard that key belongs to and returns it. poolMut
+// stays read-locked until unlockKey, so that the pool cannot grow, shrink or
+// be rebalanced, moving key to another shard, while the shard is in use.
+func (m *CacheManager) lockKey(key string) *NodeShards {
+	m.poolMut.RLock()
+	shard := m.shardFor(key)
+	shard.lock()
+	return shard
+}
+
+// unlockKey releases the locks taken by lockKey, then hands the nodes removed
+// while they were held to the release hooks. Both locks are released first,
+// since the hooks may re-enter the cache and a recursive read lock of poolMut
+// can deadlock with a waiting writer.
+func (m *CacheManager) unlockKey(shard *NodeShards) {
+	pending := shard.takePending()
+	shard.settle()
+	shard.recordHold()
+	shard.mut.Unlock()
+	m.poolMut.RUnlock()
+	m.dispose(pending)
+}
+
+// rlockKey read-locks the shard that key belongs to and returns it, keeping
+// poolMut read-locked like lockKey until runlockKey.
+func (m *CacheManager) rlockKey(key string) *NodeShards {
+	m.poolMut.RLock()
+	shard := m.shardFor(key)
+	shard.mut.RLock()
+	return shard
+}
+
+// runlockKey releases the locks taken by rlockKey.
+func (m *CacheManager) runlockKey(shard *NodeShards) {
+	shard.mut.RUnlock()
+	m.poolMut.RUnlock()
+}
+
+// groupByShard groups keys by the shard they belong to, so that batch
+// operations can take each shard lock only once. The caller must hold
+// poolMut for as long as it uses the groups.
+func (m *CacheManager) groupByShard(keys []string) map[*NodeShards][]string {
+	groups := make(map[*NodeShards][]string)
+	for _, key := range keys {
+		shard := m.shardFor(key)
+		groups[shard] = append(groups[shard], key)
 	}
-	return target
+	return groups
 }
 
 // dynamicShardScaling checks the load of shards and adds or removes shards as needed.
+// The OnShardChange callback, if any, is invoked for each change once poolMut
+// has been released, so the callback may safely query the manager.
+// Scaling is skipped while MinRebalanceInterval has not passed since the last one.
 func (m *CacheManager) dynamicShardScaling() {
+	if m.rebalancedRecently() {
+		return
+	}
+
+	var added, removed, addedTotal, removedTotal int
+	var pending []removal
+
 	m.poolMut.Lock()
-	defer m.poolMut.Unlock()
+	if m.rebalancedRecently() {
+		m.poolMut.Unlock()
+		return
+	}
 
 	var addShardNeeded bool
 	var removeShardNeeded bool
 
+	// The pool shrinks by a shard once the entries would fill one shard
+	// fewer to a quarter of NodeCap at most, and no shard is over half full,
+	// so that a skewed shard does not make the pool grow and shrink in turn.
+	total, busiest := 0, 0
 	for _, shard := range m.pool {
 		if shard.size >= m.nodeCap-2 && shard.shardSize < m.maxCost {
 			addShardNeeded = true
 		}
-		if shard.size <= m.nodeCap/4 && shard.size > 4 && shard.shardSize > m.maxCost {
-			removeShardNeeded = true
-		}
-		if addShardNeeded && removeShardNeeded {
-			break
-		}
+		total += shard.size
+		busiest = max(busiest, shard.size)
 	}
+	removeShardNeeded = !addShardNeeded && len(m.pool) > m.minPool &&
+		4*total <= (len(m.pool)-1)*m.nodeCap && 2*busiest <= m.nodeCap
 
-	if addShardNeeded {
+	if addShardNeeded && len(m.pool) < m.shardCap {
 		m.addShard()
-		m.rebalanceNodes()
+		pending = m.rebalanceNodes()
+		added = 1
+		addedTotal = len(m.pool)
 	}
 
 	if removeShardNeeded {
-		m.removeShardAndRebalance()
+		var dropped []removal
+		removed, dropped = m.removeShardAndRebalance()
+		pending = append(pending, dropped...)
+		removedTotal = len(m.pool)
+	}
+
+	if added > 0 || removed > 0 {
+		m.lastRebalance.Store(time.Now().UnixNano())
+	}
+	m.poolMut.Unlock()
+	m.dispose(pending)
+	if added > 0 {
+		m.signalRelease()
+	}
+
+	if m.onShardChange == nil {
+		return
+	}
+	if added > 0 {
+		m.onShardChange(added, addedTotal)
+	}
+	if removed > 0 {
+		m.onShardChange(-removed, removedTotal)
 	}
 }
 
-// removeShardAndRebalance removes empty shards from the pool and rebalances nodes.
-func (m *CacheManager) removeShardAndRebalance() {
-	m.poolMut.Lock()
-	defer m.poolMut.Unlock()
+// rebalancedRecently reports whether dynamic scaling last changed the pool
+// less than MinRebalanceInterval ago.
+func (m *CacheManager) rebalancedRecently() bool {
+	if m.minRebalance <= 0 {
+		return false
+	}
+	last := m.lastRebalance.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < m.minRebalance
+}
 
-	for i := len(m.pool) - 1; i >= 0; i-- {
-		shard := m.pool[i]
-		if shard.size == 0 && len(m.pool) > 2 {
-			m.pool = append(m.pool[:i], m.pool[i+1:]...)
-		}
+// minShards is the number of shards a dynamically sharded pool starts with,
+// and never shrinks below.
+const minShards = 4
+
+// removeShardAndRebalance removes the last shard of the pool, which jump
+// consistent hashing lets go while moving only its own keys, stops its
+// cleaner and moves its nodes to the shards their keys now belong to. It
+// returns the number of shards removed, none if the pool is down to
+// minPool. The pool is copied into a fresh slice so the old backing array
+// no longer references the removed shard and it can be garbage collected
+// promptly. The nodes dropped by the rebalance are returned for dispose,
+// which the caller must run once it has released poolMut.
+// The caller must hold poolMut.
+func (m *CacheManager) removeShardAndRebalance() (int, []removal) {
+	if len(m.pool) <= m.minPool {
+		return 0, nil
+	}
+
+	old := m.pool
+	for _, shard := range old {
+		shard.lock()
 	}
+	dropped := old[len(old)-1]
+	m.pool = append(make([]*NodeShards, 0, cap(old)), old[:len(old)-1]...)
+	m.redistribute(old)
 
-	m.rebalanceNodes()
+	pending := dropped.takePending()
+	dropped.unlock()
+	close(dropped.cleanerStop)
+	return 1, append(pending, m.unlockAll()...)
 }
 
 // rebalanceNodes redistributes nodes across shards to maintain balance.
-func (m *CacheManager) rebalanceNodes() {
-	totalNodes := 0
+// Every shard is emptied, including its linked list, eviction policy and node
+// index, and the live nodes, including those kept out of the LRU, are
+// reinserted into the shard their key hashes to, oldest first, so that each
+// shard's recency order is kept. Nodes keep their last used time, and stale
+// nodes are dropped instead of being moved. All shards are locked for the
+// duration. The stale nodes dropped are returned rather than handed to the
+// release hooks, since the hooks may use the cache; the caller must pass them
+// to dispose once it has released poolMut, which it must hold.
+func (m *CacheManager) rebalanceNodes() []removal {
 	for _, shard := range m.pool {
-		shard.mut.Lock()
-		totalNodes += len(shard.pool)
-		shard.mut.Unlock()
+		shard.lock()
 	}
+	m.redistribute(m.pool)
+	return m.unlockAll()
+}
 
-	allNodes := make([]*Nodes, 0, totalNodes)
-
+// unlockAll releases the lock of every shard and returns the removals queued
+// while they were held. The caller must hold poolMut.
+func (m *CacheManager) unlockAll() []removal {
+	var pending []removal
 	for _, shard := range m.pool {
-		shard.mut.Lock()
+		pending = append(pending, shard.takePending()...)
+		shard.unlock()
+	}
+	return pending
+}
+
+// redistribute does the work of rebalanceNodes, moving the nodes of the
+// shards in from, which must include every shard of the pool, into the pool.
+// The caller must hold poolMut and the lock of every shard in from.
+func (m *CacheManager) redistribute(from []*NodeShards) {
+	now := time.Now().Unix()
+	totalNodes := 0
+	for _, shard := range from {
+		totalNodes += shard.size
+	}
+
+	allNodes := make([]*Nodes, 0, totalNodes)
+	for _, shard := range from {
+		for node := shard.tail.prev; node != shard.head; node = node.prev {
+			if shard.stale(node, now) {
+				shard.record(node, removalExpired)
+				continue
+			}
+			allNodes = append(allNodes, node)
+		}
 		for _, node := range shard.pool {
+			if !node.noLRU {
+				continue
+			}
+			if shard.stale(node, now) {
+				shard.record(node, removalExpired)
+				continue
+			}
 			allNodes = append(allNodes, node)
 		}
 
-		for key, node := range shard.pool {
-			shard.evictionHeap.RemoveNode(node)
-			shard.removeNode(node)
-			delete(shard.pool, key)
-		}
-		shard.size = 0
-		shard.mut.Unlock()
+		shard.reset()
+		shard.settle()
 	}
+	sort.SliceStable(allNodes, func(i, j int) bool {
+		return allNodes[i].lastUsed < allNodes[j].lastUsed
+	})
 
 	for _, node := range allNodes {
-		idx := m.jch.Hash(node.Key) % uint64(len(m.pool))
-		shard := m.pool[idx]
+		shard := m.shardFor(node.Key)
 
-		shard.mut.Lock()
-
-		if shard.size >= shard.capacity {
-			shard.moveToTail()
-			shard.evictionHeap.RemoveNode(node)
-			shard.removeNode(node)
-		}
-		shard.shardSize += node.nodeSize
-		shard.pool[node.Key] = node
+		lastUsed := node.lastUsed
+		node.prev, node.next = nil, nil
+		shard.grow(node.nodeSize)
 		shard.addToHead(node)
+		shard.pool[node.Key] = node
 		shard.size++
+		node.lastUsed = lastUsed
+		if node.seq > shard.seq {
+			shard.seq = node.seq
+		}
+		shard.track(node)
+	}
 
-		shard.mut.Unlock()
+	for _, shard := range m.pool {
+		for shard.size > shard.capacity {
+			if shard.evict() == nil {
+				break
+			}
+		}
+		shard.evictOverCost()
 	}
 }
@@ -0,0 +1,442 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+	"weak"
+)
+
+// TestShardLookupDuringPoolGrowth grows the pool while other goroutines read
+// and write single keys, rename them and run transactions, which must neither
+// race on the pool nor leave a key outside the shard it belongs to.
+func TestShardLookupDuringPoolGrowth(t *testing.T) {
+	m := New(&Config{ShardCap: 32, NodeCap: 10000, EnableDynamicSharding: true})
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("w%d:%d", w, i)
+				m.Set(key, i, 1)
+				if v, ok := m.GetOK(key); !ok || v != i {
+					t.Errorf("GetOK(%q) = %v, %v; want %d, true", key, v, ok, i)
+				}
+				if _, err := m.Rename(key, key+":r"); err != nil {
+					t.Errorf("Rename(%q): %v", key, err)
+				}
+				m.Transact([]string{key + ":r"}, func(tx Tx) {
+					tx.Remove(key + ":r")
+				})
+			}
+		}(w)
+	}
+	for n := 5; n <= 32; n++ {
+		m.EnsureShards(n)
+	}
+	wg.Wait()
+
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if orphans := m.Orphans(); len(orphans) > 0 {
+		t.Fatalf("Orphans() = %v, want none", orphans)
+	}
+	if n := m.Len(); n != 0 {
+		t.Fatalf("Len() = %d after removing every key, want 0", n)
+	}
+}
+
+// resizingCloser grows the pool of m when it is closed.
+type resizingCloser struct {
+	m *CacheManager
+	n int
+}
+
+func (c resizingCloser) Close() error {
+	c.m.EnsureShards(c.n)
+	return nil
+}
+
+// TestHooksMayResizePool runs release hooks that grow the pool from every
+// path that removes entries across shards, which deadlocks if the hooks run
+// while the pool lock is still held. The cache is only closed once the hooks
+// have returned, since Close would block behind a deadlocked EnsureShards.
+func TestHooksMayResizePool(t *testing.T) {
+	var m *CacheManager
+	m = New(&Config{
+		ShardCap:              8,
+		NodeCap:               10,
+		EnableDynamicSharding: true,
+		CloseOnEvict:          true,
+		OnEvict: func(key string, value interface{}) {
+			m.EnsureShards(len(key))
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fill := func() {
+			for i := 0; i < 20; i++ {
+				m.Set(fmt.Sprintf("k%d", i), resizingCloser{m, 1 + i%8}, 1)
+			}
+		}
+		fill()
+		m.ClearShard(0)
+		fill()
+		m.SetShardCapacity(0, 1)
+		m.SetShardCapacity(0, 10)
+		fill()
+		m.RemovePrefix("k")
+		fill()
+		m.Clear()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("release hooks deadlocked on the pool lock")
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+}
+
+// TestOnShardChange checks that OnShardChange reports the shards added by
+// TestOnShardChange grows a dynamically sharded cache, first with
+// EnsureShards and then by filling it, empties it again, and checks that
+// OnShardChange reports every transition outside poolMut, the growth one
+// shard at a time up to ShardCap and the shrink one shard at a time down
+// to the size EnsureShards asked for.
+func TestOnShardChange(t *testing.T) {
+	type change struct{ delta, total, len int }
+	var changes []change
+	var m *CacheManager
+	m = New(&Config{
+		ShardCap:              8,
+		NodeCap:               10,
+		EnableDynamicSharding: true,
+		OnShardChange: func(delta, total int) {
+			changes = append(changes, change{delta, total, m.Len()})
+		},
+	})
+	defer m.Close()
+
+	if n := m.EnsureShards(6); n != 6 {
+		t.Fatalf("EnsureShards(6) = %d, want 6", n)
+	}
+	if changes[0] != (change{2, 6, 0}) {
+		t.Errorf("first change = %+v, want 2 shards added for a total of 6", changes[0])
+	}
+	// Writes filling the shards make dynamic sharding add the rest.
+	for i := 0; i < 50; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, time.Hour)
+	}
+	grown := len(changes)
+	if grown != 3 {
+		t.Fatalf("OnShardChange called %d times while growing, want 3", grown)
+	}
+	for i := 1; i < grown; i++ {
+		if c := changes[i]; c != (change{1, 6 + i, c.len}) {
+			t.Errorf("change %d = %+v, want 1 shard added for a total of %d", i, c, 6+i)
+		}
+	}
+
+	// Writes to a nearly empty cache make it shrink back.
+	for i := 0; i < 48; i++ {
+		m.Remove(fmt.Sprintf("key%d", i))
+	}
+	for i := 0; i < 4; i++ {
+		m.SetTTL(fmt.Sprintf("new%d", i), i, 1, time.Hour)
+	}
+	if len(changes) != grown+2 {
+		t.Fatalf("OnShardChange called %d times while shrinking, want 2", len(changes)-grown)
+	}
+	for i := grown; i < len(changes); i++ {
+		if c, want := changes[i], 8-(i-grown+1); c.delta != -1 || c.total != want {
+			t.Errorf("change %d = %+v, want 1 shard removed for a total of %d", i, c, want)
+		}
+	}
+	if n := len(m.ShardSizes()); n != 6 {
+		t.Fatalf("%d shards after shrinking, want the 6 EnsureShards asked for", n)
+	}
+	for _, key := range []string{"key48", "key49", "new0", "new3"} {
+		if _, ok := m.Peek(key); !ok {
+			t.Errorf("%s lost by the shrink", key)
+		}
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPrewarm prewarms a dynamically sharded cache and checks that the pool
+// reaches ShardCap at once, in a single OnShardChange call, and that writes
+// filling the cache and emptying it again change it no further.
+func TestPrewarm(t *testing.T) {
+	type change struct{ delta, total int }
+	var changes []change
+	m := New(&Config{
+		ShardCap:              8,
+		NodeCap:               10,
+		EnableDynamicSharding: true,
+		OnShardChange: func(delta, total int) {
+			changes = append(changes, change{delta, total})
+		},
+	})
+	defer m.Close()
+
+	m.Prewarm()
+	if n := len(m.ShardSizes()); n != 8 {
+		t.Fatalf("%d shards after Prewarm, want ShardCap", n)
+	}
+	if len(changes) != 1 || changes[0] != (change{4, 8}) {
+		t.Fatalf("OnShardChange calls = %+v, want a single one adding 4 for a total of 8", changes)
+	}
+
+	for i := 0; i < 70; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, time.Hour)
+	}
+	for i := 0; i < 70; i++ {
+		m.Remove(fmt.Sprintf("key%d", i))
+	}
+	m.SetTTL("last", 0, 1, time.Hour)
+	if len(changes) != 1 {
+		t.Fatalf("OnShardChange calls = %+v after writes, want only Prewarm's", changes)
+	}
+}
+
+// TestShrinkTrigger checks the occupancy at which dynamic sharding removes
+// a shard: not while the entries would fill one shard fewer beyond a
+// quarter of NodeCap, nor while a shard is over half full, and never below
+// the four shards a dynamically sharded pool starts with. Scaling is run by
+// hand on a pool of five shards, so that writes do not scale it on the way.
+func TestShrinkTrigger(t *testing.T) {
+	newCache := func() *CacheManager {
+		return New(&Config{
+			ShardCap: 5,
+			NodeCap:  20,
+			// Keys choose their shard by their first letter.
+			ShardFunc: func(key string, n int) int { return int(key[0]-'a') % n },
+		})
+	}
+
+	m := newCache()
+	defer m.Close()
+	// 20 entries fill four shards to a quarter of NodeCap.
+	for i := 0; i < 21; i++ {
+		m.SetTTL(fmt.Sprintf("%c%d", 'a'+i%5, i), i, 1, time.Hour)
+	}
+	m.dynamicShardScaling()
+	if n := len(m.ShardSizes()); n != 5 {
+		t.Fatalf("%d shards after scaling 21 entries, want 5", n)
+	}
+	m.Remove("a0")
+	m.dynamicShardScaling()
+	if n := len(m.ShardSizes()); n != 4 || m.Len() != 20 {
+		t.Fatalf("%d shards holding %d entries after scaling 20, want 4 holding 20", n, m.Len())
+	}
+	m.Clear()
+	m.dynamicShardScaling()
+	if n := len(m.ShardSizes()); n != 4 {
+		t.Fatalf("%d shards after scaling an empty cache, want 4", n)
+	}
+
+	skewed := newCache()
+	defer skewed.Close()
+	for i := 0; i < 11; i++ {
+		skewed.SetTTL(fmt.Sprintf("a%d", i), i, 1, time.Hour)
+	}
+	skewed.dynamicShardScaling()
+	if n := len(skewed.ShardSizes()); n != 5 {
+		t.Fatalf("%d shards after scaling a shard over half full, want 5", n)
+	}
+}
+
+// TestRemoveShardsCompactsPool grows a dynamically sharded cache with
+// cleaners to ShardCap by filling it and shrinks it back by emptying it, many
+// times over, and checks that every removed shard is released: its cleaner
+// goroutine stops, and the shard itself is garbage collected, which the
+// pool's old backing array would otherwise prevent. Every entry written
+// after a shrink stays reachable.
+func TestRemoveShardsCompactsPool(t *testing.T) {
+	m := New(&Config{ShardCap: 8, NodeCap: 10, EnableDynamicSharding: true, EnableCleaner: true})
+	defer m.Close()
+	baseline := m.goroutines.Load()
+
+	var removed []weak.Pointer[NodeShards]
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 60; i++ {
+			m.SetTTL(fmt.Sprintf("key%d", i), i, 1, time.Hour)
+		}
+		if n := len(m.ShardSizes()); n != 8 {
+			t.Fatalf("round %d: %d shards once full, want 8", round, n)
+		}
+		m.poolMut.RLock()
+		for _, shard := range m.pool[4:] {
+			removed = append(removed, weak.Make(shard))
+		}
+		m.poolMut.RUnlock()
+
+		for i := 0; i < 60; i++ {
+			m.Remove(fmt.Sprintf("key%d", i))
+		}
+		for i := 0; i < 4; i++ {
+			m.SetTTL(fmt.Sprintf("new%d", i), i, 1, time.Hour)
+		}
+		if n := len(m.ShardSizes()); n != 4 {
+			t.Fatalf("round %d: %d shards once emptied, want 4", round, n)
+		}
+		for i := 0; i < 4; i++ {
+			if v := m.Get(fmt.Sprintf("new%d", i)); v != i {
+				t.Fatalf("round %d: Get(new%d) = %v after the shrink, want %d", round, i, v, i)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.goroutines.Load() != baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines running, want the %d running before growing", m.goroutines.Load(), baseline)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i, p := range removed {
+		for p.Value() != nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("removed shard %d was never garbage collected", i)
+			}
+			runtime.GC()
+		}
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRebalanceKeepsPolicies grows the pool under every policy, which moves
+// entries between shards, and checks that the shards' lists and heaps stay
+// consistent and that every live entry can still be found and evicted.
+func TestRebalanceKeepsPolicies(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, Policy2Q, PolicyClock, PolicyLFU, PolicyCost} {
+		m := New(&Config{ShardCap: 8, NodeCap: 50, EnableDynamicSharding: true, Policy: policy})
+		for i := 0; i < 100; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, 1)
+			if i%3 == 0 {
+				m.Get(fmt.Sprintf("key%d", i))
+			}
+		}
+		m.SetTTLNoLRU("pure", 0, 1, time.Hour)
+
+		m.EnsureShards(8)
+		if err := m.Verify(); err != nil {
+			t.Fatalf("policy %d: %v", policy, err)
+		}
+		if orphans := m.Orphans(); len(orphans) > 0 {
+			t.Fatalf("policy %d: Orphans() = %v after rebalancing", policy, orphans)
+		}
+		if _, ok := m.Peek("pure"); !ok {
+			t.Fatalf("policy %d: pure TTL entry lost by the rebalance", policy)
+		}
+
+		// Every shard can still evict down to a single entry.
+		for i := 0; i < 8; i++ {
+			if err := m.SetShardCapacity(i, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := m.Verify(); err != nil {
+			t.Fatalf("policy %d: %v after evicting", policy, err)
+		}
+		m.Close()
+	}
+}
+
+// TestMinRebalanceInterval fills a dynamically sharded cache and checks that
+// scaling adds a single shard within MinRebalanceInterval, however often it
+// is triggered, and resumes once the interval has passed.
+func TestMinRebalanceInterval(t *testing.T) {
+	var changes int
+	m := New(&Config{
+		ShardCap:              8,
+		NodeCap:               10,
+		EnableDynamicSharding: true,
+		MinRebalanceInterval:  time.Hour,
+		OnShardChange:         func(int, int) { changes++ },
+	})
+	defer m.Close()
+	before := len(m.ShardSizes())
+	for i := 0; i < 50; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, time.Hour)
+	}
+	m.dynamicShardScaling()
+	if n := len(m.ShardSizes()); n != before+1 || changes != 1 {
+		t.Fatalf("%d shards after %d changes within the interval, want %d after 1", n, changes, before+1)
+	}
+
+	m.lastRebalance.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	m.dynamicShardScaling()
+	if n := len(m.ShardSizes()); n != before+2 || changes != 2 {
+		t.Fatalf("%d shards after %d changes once the interval passed, want %d after 2", n, changes, before+2)
+	}
+}
+
+// TestScalingRacesNoShardPath writes over MaxCost from several goroutines
+// while dynamic sharding grows the pool, and runs every path that touches
+// shards outside the key paths alongside: global eviction, lazy values, the
+// cleaner, RangeLRU and EvictionCandidates. Run under -race, it checks that
+// none of them touches a shard while scaling reads it.
+func TestScalingRacesNoShardPath(t *testing.T) {
+	m := New(&Config{ShardCap: 64, NodeCap: 4, MaxCost: 40, EnableDynamicSharding: true})
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("w%d-%d", w, i%60)
+				if i%5 == 0 {
+					m.SetLazy(key, func() (interface{}, uint64) { return i, 1 }, time.Hour)
+					m.Get(key)
+					continue
+				}
+				m.SetTTL(key, i, 1, time.Duration(i%3)*time.Second)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m.poolMut.RLock()
+			shards := append([]*NodeShards(nil), m.pool...)
+			m.poolMut.RUnlock()
+			for _, shard := range shards {
+				shard.cleanExpired()
+			}
+			m.RangeLRU(func(string, interface{}) bool { return true })
+			m.EvictionCandidates(5)
+		}
+	}()
+	wg.Wait()
+
+	if c := m.Cost(); c > 40 {
+		t.Fatalf("Cost() = %d, want at most MaxCost", c)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
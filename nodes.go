@@ -5,6 +5,8 @@
 
 package cerebru
 
+import "time"
+
 // Nodes represents a single entry in the cache.
 // Each node contains a key-value pair, pointers for linked list traversal,
 // and metadata for cache management policies.
@@ -27,11 +29,94 @@ type Nodes struct {
 	// should expire and be considered invalid.
 	expiredAt int64
 
+	// ttl is the time-to-live the node was last given, used to refresh
+	// expiredAt on access when sliding TTLs are enabled.
+	ttl time.Duration
+
+	// createdAt is the timestamp (in Unix time) of when the cache entry was
+	// first added to a shard.
+	createdAt int64
+
+	// epoch is the cache generation the node was last written in.
+	epoch uint64
+
+	// seq is the insertion sequence number of the node within its shard.
+	// It breaks ties between nodes with the same lastUsed timestamp so that
+	// the earliest inserted node is evicted first.
+	seq uint64
+
 	// lastUsed is the timestamp (in Unix time) of the last time the cache entry
 	// was accessed or modified. This is useful for eviction policies.
 	lastUsed int64
 
+	// protected marks a node that has been accessed again after insertion
+	// and not demoted since. Under Policy2Q, protected nodes are only
+	// evicted once no probationary node is left in the shard.
+	protected bool
+
+	// referenced is the reference bit of PolicyClock, set on access and
+	// cleared when the clock hand passes the node.
+	referenced bool
+
+	// freq counts the accesses to the node under PolicyLFU. It stays zero
+	// under the other policies.
+	freq uint64
+
+	// weight ranks the node for eviction, lower weights going first. Under
+	// PolicyCost it is the node's cost; it stays zero under the other policies
+	// unless given explicitly by SetWeighted.
+	weight uint64
+
+	// weighted marks a node whose weight was given by SetWeighted, so that
+	// PolicyCost does not replace it with the node's cost.
+	weighted bool
+
+	// pinned marks a node that must never be evicted by capacity or cost
+	// pressure. Pinned nodes still expire and can still be removed.
+	pinned bool
+
+	// readOnly marks a node stored by SetReadOnly, whose value writes
+	// reject instead of overwriting. It can still be removed or evicted.
+	readOnly bool
+
+	// heapIndex is the position of the node in its shard's eviction heap,
+	// kept up to date by the heap as it moves nodes around, or -1 once the
+	// node has been popped from it.
+	heapIndex int
+
+	// noLRU marks a pure TTL node, stored by SetTTLNoLRU. It is kept in the
+	// pool but out of the linked list and the eviction heap, so it is never
+	// evicted and only leaves the cache by expiring or being removed.
+	noLRU bool
+
 	// nodeSize represents the size of the value stored in this node,
 	// which can be useful for managing memory and cache size limits.
 	nodeSize uint64
 }
+
+// Size returns the size of the entry, as counted against MaxCost. It is
+// meant for EvictionPolicy implementations.
+func (n *Nodes) Size() uint64 {
+	return n.nodeSize
+}
+
+// Weight returns the eviction weight given to the entry by SetWeighted, and
+// whether it was given one. It is meant for EvictionPolicy implementations.
+func (n *Nodes) Weight() (uint64, bool) {
+	return n.weight, n.weighted
+}
+
+// expired reports whether the node carries an expiry at or before now.
+// Nodes with an expiry of zero never expire.
+func (n *Nodes) expired(now int64) bool {
+	return n.expiredAt > 0 && n.expiredAt <= now
+}
+
+// expiryFor converts a time-to-live into the Unix timestamp stored in expiredAt.
+// A ttl of zero or less yields zero, meaning the node never expires.
+func expiryFor(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).Unix()
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// Default shard and node capacities used by NewWithOptions.
+const (
+	DefaultShardCap = 16
+	DefaultNodeCap  = 1024
+)
+
+// Option configures a cache built by NewWithOptions. Each Option sets one or
+// a few related fields of the Config the cache is built from.
+type Option func(*Config)
+
+// NewWithOptions creates a CacheManager from functional options. It starts
+// from a Config with DefaultShardCap shards of DefaultNodeCap nodes, applies
+// opts in order, and builds the cache as New would. It returns
+// ErrInvalidCapacity if the resulting shard or node capacity is not positive.
+func NewWithOptions(opts ...Option) (*CacheManager, error) {
+	cfg := Config{
+		ShardCap: DefaultShardCap,
+		NodeCap:  DefaultNodeCap,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.ShardCap <= 0 || cfg.NodeCap <= 0 {
+		return nil, ErrInvalidCapacity
+	}
+	return New(&cfg), nil
+}
+
+// WithConfig replaces the whole Config with cfg. Options applied after it
+// override individual fields.
+func WithConfig(cfg Config) Option {
+	return func(c *Config) { *c = cfg }
+}
+
+// WithShardCap sets Config.ShardCap.
+func WithShardCap(n int) Option {
+	return func(c *Config) { c.ShardCap = n }
+}
+
+// WithNodeCap sets Config.NodeCap.
+func WithNodeCap(n int) Option {
+	return func(c *Config) { c.NodeCap = n }
+}
+
+// WithMaxCost sets Config.MaxCost.
+func WithMaxCost(cost uint64) Option {
+	return func(c *Config) { c.MaxCost = cost }
+}
+
+// WithCleaner sets Config.EnableCleaner.
+func WithCleaner(enabled bool) Option {
+	return func(c *Config) { c.EnableCleaner = enabled }
+}
+
+// WithCleanerBudget sets Config.CleanerBudget.
+func WithCleanerBudget(n int) Option {
+	return func(c *Config) { c.CleanerBudget = n }
+}
+
+// WithCleanerYieldUnderLoad sets Config.CleanerYieldUnderLoad.
+func WithCleanerYieldUnderLoad(enabled bool) Option {
+	return func(c *Config) { c.CleanerYieldUnderLoad = enabled }
+}
+
+// WithDynamicSharding sets Config.EnableDynamicSharding.
+func WithDynamicSharding(enabled bool) Option {
+	return func(c *Config) { c.EnableDynamicSharding = enabled }
+}
+
+// WithOnShardChange sets Config.OnShardChange.
+func WithOnShardChange(fn func(delta int, total int)) Option {
+	return func(c *Config) { c.OnShardChange = fn }
+}
+
+// WithShardFunc sets Config.ShardFunc.
+func WithShardFunc(fn func(key string, n int) int) Option {
+	return func(c *Config) { c.ShardFunc = fn }
+}
+
+// WithEvictionPolicy sets Config.Policy.
+func WithEvictionPolicy(p Policy) Option {
+	return func(c *Config) { c.Policy = p }
+}
+
+// WithCustomPolicy sets Config.CustomPolicy.
+func WithCustomPolicy(fn func() EvictionPolicy) Option {
+	return func(c *Config) { c.CustomPolicy = fn }
+}
+
+// WithCloseOnEvict sets Config.CloseOnEvict.
+func WithCloseOnEvict(enabled bool) Option {
+	return func(c *Config) { c.CloseOnEvict = enabled }
+}
+
+// WithStrictCost sets Config.StrictCost.
+func WithStrictCost(enabled bool) Option {
+	return func(c *Config) { c.StrictCost = enabled }
+}
+
+// WithStats sets Config.StatsInterval and Config.OnStats.
+func WithStats(interval time.Duration, fn func(Stats)) Option {
+	return func(c *Config) {
+		c.StatsInterval = interval
+		c.OnStats = fn
+	}
+}
+
+// WithCodec enables Config.EncodeValues with the given Codec. A nil codec
+// selects the default GobCodec.
+func WithCodec(codec Codec) Option {
+	return func(c *Config) {
+		c.EncodeValues = true
+		c.Codec = codec
+	}
+}
+
+// WithCompression sets Config.CompressMinSize.
+func WithCompression(minSize uint64) Option {
+	return func(c *Config) { c.CompressMinSize = minSize }
+}
+
+// WithShouldCompress sets Config.ShouldCompress.
+func WithShouldCompress(fn func(key string, value interface{}, size uint64) bool) Option {
+	return func(c *Config) { c.ShouldCompress = fn }
+}
+
+// WithWriteCoalesceWindow sets Config.WriteCoalesceWindow.
+func WithWriteCoalesceWindow(window time.Duration) Option {
+	return func(c *Config) { c.WriteCoalesceWindow = window }
+}
+
+// WithAccessLog sets Config.AccessLogSize.
+func WithAccessLog(size int) Option {
+	return func(c *Config) { c.AccessLogSize = size }
+}
+
+// WithTTLRules appends rules to Config.TTLRules.
+func WithTTLRules(rules ...TTLRule) Option {
+	return func(c *Config) { c.TTLRules = append(c.TTLRules, rules...) }
+}
+
+// WithMaxGoroutines sets Config.MaxGoroutines.
+func WithMaxGoroutines(n int) Option {
+	return func(c *Config) { c.MaxGoroutines = n }
+}
+
+// WithRequireExplicitTTL sets Config.RequireExplicitTTL.
+func WithRequireExplicitTTL(enabled bool) Option {
+	return func(c *Config) { c.RequireExplicitTTL = enabled }
+}
+
+// WithTrackLockHold sets Config.TrackLockHold.
+func WithTrackLockHold(enabled bool) Option {
+	return func(c *Config) { c.TrackLockHold = enabled }
+}
+
+// WithFairLocking sets Config.FairLocking.
+func WithFairLocking(enabled bool) Option {
+	return func(c *Config) { c.FairLocking = enabled }
+}
+
+// WithSingleFlight sets Config.SingleFlight.
+func WithSingleFlight(group SingleFlight) Option {
+	return func(c *Config) { c.SingleFlight = group }
+}
+
+// WithOnEvict sets Config.OnEvict.
+func WithOnEvict(fn func(key string, value interface{})) Option {
+	return func(c *Config) { c.OnEvict = fn }
+}
+
+// WithOnExpire sets Config.OnExpire.
+func WithOnExpire(fn func(key string, value interface{})) Option {
+	return func(c *Config) { c.OnExpire = fn }
+}
+
+// WithEvictHysteresis sets Config.EvictHysteresis.
+func WithEvictHysteresis(fraction float64) Option {
+	return func(c *Config) { c.EvictHysteresis = fraction }
+}
+
+// WithMemoryPressure sets Config.MemoryPressureThreshold and
+// Config.MemoryPressureInterval.
+func WithMemoryPressure(threshold uint64, interval time.Duration) Option {
+	return func(c *Config) {
+		c.MemoryPressureThreshold = threshold
+		c.MemoryPressureInterval = interval
+	}
+}
+
+// WithHeapInUse sets Config.HeapInUse.
+func WithHeapInUse(fn func() uint64) Option {
+	return func(c *Config) { c.HeapInUse = fn }
+}
+
+// WithMinRebalanceInterval sets Config.MinRebalanceInterval.
+func WithMinRebalanceInterval(d time.Duration) Option {
+	return func(c *Config) { c.MinRebalanceInterval = d }
+}
+
+// WithCheckpoint sets Config.CheckpointPath and Config.CheckpointInterval.
+func WithCheckpoint(path string, interval time.Duration) Option {
+	return func(c *Config) {
+		c.CheckpointPath = path
+		c.CheckpointInterval = interval
+	}
+}
+
+// WithSlidingTTL sets Config.SlidingTTL.
+func WithSlidingTTL(enabled bool) Option {
+	return func(c *Config) { c.SlidingTTL = enabled }
+}
+
+// WithTTLJitter sets Config.TTLJitter.
+func WithTTLJitter(fraction float64) Option {
+	return func(c *Config) { c.TTLJitter = fraction }
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "testing"
+
+// TestNewWithOptions checks the defaults of NewWithOptions, that options
+// apply in order over WithConfig, and that invalid capacities are rejected.
+func TestNewWithOptions(t *testing.T) {
+	m, err := NewWithOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.pool) != DefaultShardCap || m.nodeCap != DefaultNodeCap {
+		t.Errorf("defaults = %d shards of %d nodes, want %d of %d", len(m.pool), m.nodeCap, DefaultShardCap, DefaultNodeCap)
+	}
+	m.Close()
+
+	m, err = NewWithOptions(
+		WithShardCap(100),
+		WithConfig(Config{ShardCap: 2, NodeCap: 5, Policy: PolicyLFU}),
+		WithNodeCap(7),
+		WithMaxCost(64),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.pool) != 2 || m.nodeCap != 7 || m.policy != PolicyLFU || m.maxCost != 64 {
+		t.Errorf("built %d shards of %d nodes, policy %d, MaxCost %d; want 2, 7, LFU and 64",
+			len(m.pool), m.nodeCap, m.policy, m.maxCost)
+	}
+	m.Close()
+
+	for _, opt := range []Option{WithShardCap(0), WithNodeCap(-1)} {
+		if m, err := NewWithOptions(opt); err != ErrInvalidCapacity || m != nil {
+			t.Errorf("NewWithOptions with an invalid capacity = %v, %v; want nil, ErrInvalidCapacity", m, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+// Package otel exports cerebru cache statistics as OpenTelemetry metrics.
+package otel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bluespada/cerebru"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Register creates observable instruments on meter that report the
+// statistics of cache: hits, misses and evictions as cumulative counters,
+// and the current entry count and total cost as gauges. The values are read
+// from cache.Stats once per collection. The counters add up the change of
+// each statistic since the last collection, so they keep growing when
+// ResetStats sets the statistics back to zero. The returned Registration
+// can be used to unregister the instruments' callback.
+func Register(meter metric.Meter, cache *cerebru.CacheManager) (metric.Registration, error) {
+	hits, err := meter.Int64ObservableCounter(
+		"cerebru.cache.hits",
+		metric.WithDescription("Number of reads that found a live entry."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	misses, err := meter.Int64ObservableCounter(
+		"cerebru.cache.misses",
+		metric.WithDescription("Number of reads that found no live entry."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	evictions, err := meter.Int64ObservableCounter(
+		"cerebru.cache.evictions",
+		metric.WithDescription("Number of entries evicted to make room for others."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := meter.Int64ObservableGauge(
+		"cerebru.cache.entries",
+		metric.WithDescription("Number of entries currently held by the cache."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := meter.Int64ObservableGauge(
+		"cerebru.cache.bytes",
+		metric.WithDescription("Total cost of the entries currently held by the cache."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mut                             sync.Mutex
+		hitTotal, missTotal, evictTotal counter
+	)
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := cache.Stats()
+
+		mut.Lock()
+		o.ObserveInt64(hits, hitTotal.observe(stats.Hits))
+		o.ObserveInt64(misses, missTotal.observe(stats.Misses))
+		o.ObserveInt64(evictions, evictTotal.observe(stats.Evictions))
+		mut.Unlock()
+
+		o.ObserveInt64(entries, int64(stats.Entries))
+		o.ObserveInt64(bytes, int64(stats.Bytes))
+		return nil
+	}, hits, misses, evictions, entries, bytes)
+}
+
+// counter turns a statistic that ResetStats sets back to zero into the
+// monotonic total an OpenTelemetry counter must report.
+type counter struct {
+	last, total uint64
+}
+
+// observe adds the change of the statistic since its last value to the
+// total and returns the total. A value below the last one means the
+// statistics were reset in between, and counts in full.
+func (c *counter) observe(value uint64) int64 {
+	if value >= c.last {
+		c.total += value - c.last
+	} else {
+		c.total += value
+	}
+	c.last = value
+	return int64(c.total)
+}
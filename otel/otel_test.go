@@ -0,0 +1,120 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package otel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bluespada/cerebru"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newMeter returns a meter of an SDK meter provider, with the manual reader
+// that collects its instruments.
+func newMeter(t *testing.T) (*sdkmetric.ManualReader, *sdkmetric.MeterProvider) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return reader, provider
+}
+
+// collect runs a collection of reader and returns the int64 value of every
+// instrument by name.
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				if !data.IsMonotonic || data.Temporality != metricdata.CumulativeTemporality {
+					t.Errorf("%s is not a cumulative counter", m.Name)
+				}
+				values[m.Name] = data.DataPoints[0].Value
+			case metricdata.Gauge[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+			default:
+				t.Errorf("%s has unexpected data %T", m.Name, m.Data)
+			}
+		}
+	}
+	return values
+}
+
+// TestCountersSurviveResetStats checks that the exported counters keep
+// growing across ResetStats, while the gauges follow the cache.
+func TestCountersSurviveResetStats(t *testing.T) {
+	cache := cerebru.New(&cerebru.Config{ShardCap: 1, NodeCap: 10})
+	defer cache.Close()
+	reader, provider := newMeter(t)
+	if _, err := Register(provider.Meter("test"), cache); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.Set("a", 1, 4)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("missing")
+	got := collect(t, reader)
+	if got["cerebru.cache.hits"] != 2 || got["cerebru.cache.misses"] != 1 {
+		t.Fatalf("first collection = %v, want 2 hits and 1 miss", got)
+	}
+
+	cache.ResetStats()
+	cache.Get("a")
+	got = collect(t, reader)
+	if got["cerebru.cache.hits"] != 3 {
+		t.Fatalf("hits = %d after ResetStats and a hit, want 3", got["cerebru.cache.hits"])
+	}
+	if got["cerebru.cache.misses"] != 1 {
+		t.Fatalf("misses = %d after ResetStats, want 1", got["cerebru.cache.misses"])
+	}
+	if got["cerebru.cache.entries"] != 1 || got["cerebru.cache.bytes"] != 4 {
+		t.Fatalf("gauges = %v, want 1 entry of 4 bytes", got)
+	}
+}
+
+// TestRegisterReportsStats checks that every instrument reports the matching
+// statistic of the cache, evictions included.
+func TestRegisterReportsStats(t *testing.T) {
+	cache := cerebru.New(&cerebru.Config{ShardCap: 1, NodeCap: 2})
+	defer cache.Close()
+	reader, provider := newMeter(t)
+	if _, err := Register(provider.Meter("test"), cache); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), i, 3)
+	}
+	cache.Get("key4")
+	cache.Get("key0")
+
+	want := map[string]int64{
+		"cerebru.cache.hits":      1,
+		"cerebru.cache.misses":    1,
+		"cerebru.cache.evictions": 3,
+		"cerebru.cache.entries":   2,
+		"cerebru.cache.bytes":     6,
+	}
+	got := collect(t, reader)
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %d, want %d", name, got[name], value)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("collected %v, want exactly %v", got, want)
+	}
+}
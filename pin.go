@@ -0,0 +1,51 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "time"
+
+// Pin protects the entry stored under key from eviction by capacity or cost
+// pressure. A pinned entry still expires and can still be removed. It returns
+// whether the key was present and unexpired.
+func (m *CacheManager) Pin(key string) bool {
+	return m.setPinned(key, true)
+}
+
+// Unpin makes the entry stored under key eligible for eviction again.
+// It returns whether the key was present and unexpired.
+func (m *CacheManager) Unpin(key string) bool {
+	return m.setPinned(key, false)
+}
+
+// IsPinned reports whether the entry stored under key is present, unexpired
+// and pinned, which explains why it survives eviction.
+func (m *CacheManager) IsPinned(key string) bool {
+	shard := m.rlockKey(key)
+	defer m.runlockKey(shard)
+
+	node, exists := shard.pool[key]
+	return exists && !shard.stale(node, time.Now().Unix()) && node.pinned
+}
+
+// setPinned updates the pinned flag of the entry stored under key, taking a
+// pinned entry out of the eviction policy and handing an unpinned one back.
+func (m *CacheManager) setPinned(key string, pinned bool) bool {
+	defer m.reserve(0)
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	m.applyPending(shard, key)
+	node, ok := shard.lookup(key, time.Now().Unix())
+	if !ok {
+		return false
+	}
+	if node.pinned != pinned {
+		shard.untrack(node)
+		node.pinned = pinned
+		shard.track(node)
+	}
+	return true
+}
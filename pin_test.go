@@ -0,0 +1,91 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIsPinned pins and unpins a key and checks that IsPinned and Inspect
+// follow, and that a pinned entry survives eviction.
+func TestIsPinned(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 2})
+	defer m.Close()
+
+	m.Set("a", 1, 1)
+	if !m.Pin("a") {
+		t.Fatal("Pin(a) = false, want true")
+	}
+	if !m.IsPinned("a") {
+		t.Fatal("IsPinned(a) = false after Pin, want true")
+	}
+	if info, ok := m.Inspect("a"); !ok || !info.Pinned || info.Evictable {
+		t.Fatalf("Inspect(a) = %+v, %v; want pinned and not evictable", info, ok)
+	}
+
+	m.Set("b", 2, 1)
+	m.Set("c", 3, 1)
+	if _, ok := m.Peek("a"); !ok {
+		t.Fatal("pinned a was evicted")
+	}
+
+	if !m.Unpin("a") {
+		t.Fatal("Unpin(a) = false, want true")
+	}
+	if m.IsPinned("a") {
+		t.Fatal("IsPinned(a) = true after Unpin, want false")
+	}
+	if info, ok := m.Inspect("a"); !ok || info.Pinned || !info.Evictable {
+		t.Fatalf("Inspect(a) = %+v, %v; want unpinned and evictable", info, ok)
+	}
+	if m.IsPinned("missing") || m.Pin("missing") {
+		t.Fatal("missing key reported as pinned")
+	}
+}
+
+// TestInspect checks the metadata Inspect reports for an entry, and that a
+// missing key is reported as absent.
+func TestInspect(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 10, Policy: PolicyCost})
+	defer m.Close()
+
+	before := time.Now().Truncate(time.Second)
+	m.SetTTL("ttl", 1, 3, time.Hour)
+	m.SetWeighted("weighted", 2, 1, 9)
+	m.SetTTLNoLRU("nolru", 3, 1, time.Hour)
+	m.SetReadOnly("readonly", 4, 1)
+
+	info, ok := m.Inspect("ttl")
+	if !ok {
+		t.Fatal("Inspect(ttl) reported the key missing")
+	}
+	if info.Key != "ttl" || info.Size != 3 || info.TTL != time.Hour || info.Weighted || !info.Evictable {
+		t.Fatalf("Inspect(ttl) = %+v", info)
+	}
+	if info.CreatedAt.Before(before) || info.ExpiresAt.Before(before.Add(time.Hour)) {
+		t.Fatalf("Inspect(ttl) = %+v, want it created after %v and expiring an hour later", info, before)
+	}
+	m.poolMut.RLock()
+	shard := m.shardIndex("ttl")
+	m.poolMut.RUnlock()
+	if info.Shard != shard {
+		t.Fatalf("Inspect(ttl).Shard = %d, want %d", info.Shard, shard)
+	}
+
+	if info, _ := m.Inspect("weighted"); !info.Weighted || info.Weight != 9 {
+		t.Fatalf("Inspect(weighted) = %+v, want weight 9", info)
+	}
+	if info, _ := m.Inspect("nolru"); info.Evictable {
+		t.Fatalf("Inspect(nolru) = %+v, want it not evictable", info)
+	}
+	if info, _ := m.Inspect("readonly"); !info.ReadOnly || !info.ExpiresAt.IsZero() {
+		t.Fatalf("Inspect(readonly) = %+v, want read-only and never expiring", info)
+	}
+	if _, ok := m.Inspect("missing"); ok {
+		t.Fatal("Inspect(missing) reported the key present")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+// EvictionPolicy decides which entry a shard evicts when it is over capacity
+// or over its cost budget. Every shard owns its own EvictionPolicy and calls
+// it with the shard's write lock held, so an implementation needs no locking
+// of its own, and must not call back into the cache. Pinned entries and pure
+// TTL entries stored by SetTTLNoLRU are never handed to the policy: pinning
+// an entry is reported to it as OnEvict, and unpinning it as OnInsert.
+// Set Config.CustomPolicy to use an EvictionPolicy of your own.
+type EvictionPolicy interface {
+	// OnInsert is called when node enters the shard.
+	OnInsert(node *Nodes)
+
+	// OnAccess is called when node is read, or written again, while it
+	// is in the shard.
+	OnAccess(node *Nodes)
+
+	// OnEvict is called when node leaves the shard, whether it is evicted,
+	// expires or is removed, before the shard unlinks it.
+	OnEvict(node *Nodes)
+
+	// Victim returns the node the shard should evict next, or nil if none
+	// should be. It must only return nodes handed to OnInsert and not yet
+	// to OnEvict. Victim may be called without the node being evicted
+	// afterwards, such as when victims of several shards are compared, so
+	// it must not forget the node; the shard calls OnEvict once it does
+	// evict it.
+	Victim() *Nodes
+}
+
+// Policy selects one of the built-in eviction policies.
+type Policy int
+
+const (
+	// PolicyLRU evicts the least recently used entry. This is the default.
+	PolicyLRU Policy = iota
+
+	// Policy2Q is a segmented LRU. New entries enter a probationary segment
+	// and are promoted to a protected segment on their second access.
+	// Probationary entries are always evicted first, so a one-time scan
+	// over many keys cannot push the frequently used working set out of
+	// the cache. The protected segment holds at most 80% of a shard's
+	// capacity; when it overflows, its least recently used entry is
+	// demoted back to probation, so new entries are still admitted once
+	// the working set has been promoted.
+	Policy2Q
+
+	// PolicyClock approximates LRU with the CLOCK (second-chance)
+	// algorithm. Instead of an eviction heap and its index, every entry
+	// carries a single reference bit, set on access; a clock hand sweeps
+	// the entries from the oldest, clearing set bits and evicting the first
+	// entry whose bit is already clear. This cuts the per-entry memory
+	// overhead at the cost of a less exact recency order.
+	PolicyClock
+
+	// PolicyLFU evicts the least frequently used entry. Every entry counts
+	// its accesses, and the entry with the fewest is evicted first, ties
+	// going to the least recently used. Entries read often survive scans
+	// over many keys read only once, which would flush them out under LRU.
+	PolicyLFU
+
+	// PolicyCost evicts the cheapest entry first: the one with the lowest
+	// cost, as given to SetWithCost or as the size passed to the other
+	// writes, or the weight given to SetWeighted, ties going to the least
+	// recently used. Entries that are expensive to recompute are kept the
+	// longest.
+	PolicyCost
+)
+
+// newEvictionPolicy returns a new instance of the built-in policy p for the
+// shard ns. Unknown values fall back to PolicyLRU.
+func newEvictionPolicy(p Policy, ns *NodeShards) EvictionPolicy {
+	switch p {
+	case Policy2Q:
+		return &twoQPolicy{ns: ns}
+	case PolicyClock:
+		return &clockPolicy{ns: ns}
+	case PolicyLFU:
+		return &heapPolicy{counting: true}
+	case PolicyCost:
+		return &heapPolicy{byCost: true}
+	}
+	return &lruPolicy{ns: ns}
+}
+
+// evictionPolicy returns a new eviction policy for the shard ns: one made by
+// CustomPolicy if it is set and returns one, otherwise the built-in policy
+// Policy selects.
+func (m *CacheManager) evictionPolicy(ns *NodeShards) EvictionPolicy {
+	if m.customPolicy != nil {
+		if p := m.customPolicy(); p != nil {
+			return p
+		}
+	}
+	return newEvictionPolicy(m.policy, ns)
+}
+
+// weighsEntries reports whether the cache's eviction policy can make use of
+// the weights given by SetWeighted: a custom policy, or a built-in one other
+// than PolicyLRU and PolicyClock.
+func (m *CacheManager) weighsEntries() bool {
+	if m.customPolicy != nil {
+		return true
+	}
+	switch m.policy {
+	case Policy2Q, PolicyLFU, PolicyCost:
+		return true
+	}
+	return false
+}
+
+// The built-in policies implement some of the following interfaces on top of
+// EvictionPolicy. Shards fall back to a plain behaviour for policies that do
+// not, such as those set with Config.CustomPolicy.
+
+// inPlacePolicy is implemented by policies that keep accessed nodes in place
+// in the shard's linked list rather than moving them to its head, such as
+// CLOCK, whose hand sweeps the list in insertion order.
+type inPlacePolicy interface {
+	inPlace()
+}
+
+// peekingPolicy is implemented by policies that can tell their next victim
+// without changing any state, so that it can be asked under a read lock.
+// Other policies are asked through Victim under the write lock.
+type peekingPolicy interface {
+	peek() *Nodes
+}
+
+// orderedPolicy is implemented by policies whose eviction order differs from
+// the shard's linked list. coldest returns up to n of their nodes for which
+// keep is true, in the order they would be evicted, without changing any
+// state. Other policies are assumed to evict the least recently used first.
+type orderedPolicy interface {
+	coldest(n int, keep func(*Nodes) bool) []*Nodes
+}
+
+// bulkPolicy is implemented by policies that can defer their bookkeeping
+// while many nodes leave the shard at once: OnEvict calls made between
+// beginBulk and endBulk may be applied in a single pass by endBulk.
+type bulkPolicy interface {
+	beginBulk()
+	endBulk()
+}
+
+// verifiedPolicy is implemented by policies with internal structures that
+// Verify and Orphans can check. tracks reports whether node, handed to
+// OnInsert, is held by the policy, and verify checks the policy against the
+// nodes of the shard ns.
+type verifiedPolicy interface {
+	tracks(node *Nodes) bool
+	verify(ns *NodeShards) error
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// protectedShare is the share of a shard's capacity that Policy2Q's protected
+// segment may fill. The rest is left to the probationary segment, so that new
+// entries always have room to prove themselves.
+const protectedShare = 0.8
+
+// twoQPolicy implements Policy2Q with one EvictionHeap per segment. New nodes
+// enter the probationary heap and move to the protected heap on their next
+// access. Once the protected heap holds more than protectedShare of the
+// shard's capacity, its least recently used node is demoted back to
+// probation, where it is evicted first unless it is accessed again.
+type twoQPolicy struct {
+	ns                   *NodeShards
+	probation, protected EvictionHeap
+
+	// bulk defers heap maintenance of OnEvict to endBulk.
+	bulk bool
+}
+
+// limit returns how many nodes the protected segment may hold.
+func (p *twoQPolicy) limit() int {
+	return int(float64(p.ns.capacity) * protectedShare)
+}
+
+// demote moves the least recently used protected nodes to probation until
+// the protected segment fits its limit.
+func (p *twoQPolicy) demote() {
+	for p.protected.Len() > p.limit() {
+		node := heap.Pop(&p.protected).(*Nodes)
+		node.protected = false
+		heap.Push(&p.probation, node)
+	}
+}
+
+// OnInsert implements EvictionPolicy, pushing the node onto the heap of its
+// segment. New nodes are probationary; nodes handed back after being pinned
+// or redistributed keep the segment they had.
+func (p *twoQPolicy) OnInsert(node *Nodes) {
+	if !node.protected {
+		heap.Push(&p.probation, node)
+		return
+	}
+	heap.Push(&p.protected, node)
+	p.demote()
+}
+
+// OnAccess implements EvictionPolicy, promoting a probationary node to the
+// protected segment, or updating a protected node's position in its heap.
+func (p *twoQPolicy) OnAccess(node *Nodes) {
+	if node.protected {
+		if p.protected.holds(node) {
+			heap.Fix(&p.protected, node.heapIndex)
+		}
+		return
+	}
+	p.probation.RemoveNode(node)
+	node.protected = true
+	heap.Push(&p.protected, node)
+	p.demote()
+}
+
+// OnEvict implements EvictionPolicy, removing the node from its heap, or
+// only marking it for endBulk during a bulk removal.
+func (p *twoQPolicy) OnEvict(node *Nodes) {
+	if p.bulk {
+		node.heapIndex = -1
+		return
+	}
+	if node.protected {
+		p.protected.RemoveNode(node)
+	} else {
+		p.probation.RemoveNode(node)
+	}
+}
+
+// Victim implements EvictionPolicy, returning the least recently used
+// probationary node, or the least recently used protected node if no node
+// is on probation.
+func (p *twoQPolicy) Victim() *Nodes {
+	h := p.probation
+	if h.Len() == 0 {
+		h = p.protected
+	}
+	if h.Len() == 0 || h[0].pinned {
+		return nil
+	}
+	return h[0]
+}
+
+// peek implements peekingPolicy; Victim changes nothing.
+func (p *twoQPolicy) peek() *Nodes {
+	return p.Victim()
+}
+
+// coldest implements orderedPolicy by sorting a copy of both heaps. Since
+// evictsBefore ranks probationary nodes first, the probationary nodes come
+// out ahead of the protected ones.
+func (p *twoQPolicy) coldest(n int, keep func(*Nodes) bool) []*Nodes {
+	ordered := append(append([]*Nodes(nil), p.probation...), p.protected...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return evictsBefore(ordered[i], ordered[j])
+	})
+
+	var nodes []*Nodes
+	for _, node := range ordered {
+		if len(nodes) == n {
+			break
+		}
+		if keep(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// beginBulk implements bulkPolicy.
+func (p *twoQPolicy) beginBulk() {
+	p.bulk = true
+}
+
+// endBulk implements bulkPolicy, dropping the nodes evicted since beginBulk
+// from both heaps and restoring their order in a single pass each.
+func (p *twoQPolicy) endBulk() {
+	p.bulk = false
+	p.probation.compact()
+	p.protected.compact()
+}
+
+// tracks implements verifiedPolicy.
+func (p *twoQPolicy) tracks(node *Nodes) bool {
+	if node.protected {
+		return p.protected.holds(node)
+	}
+	return p.probation.holds(node)
+}
+
+// verify implements verifiedPolicy: the heaps must hold exactly the shard's
+// nodes that can be evicted, each in the heap of its segment at its indexed
+// position, and both heaps must be in heap order.
+func (p *twoQPolicy) verify(ns *NodeShards) error {
+	tracked := 0
+	for _, node := range ns.pool {
+		if node.noLRU || node.pinned {
+			continue
+		}
+		tracked++
+		if !p.tracks(node) {
+			return fmt.Errorf("node %q is not at its indexed position in its 2Q segment", node.Key)
+		}
+	}
+	if held := p.probation.Len() + p.protected.Len(); held != tracked {
+		return fmt.Errorf("2Q segments hold %d nodes but pool holds %d", held, tracked)
+	}
+	if err := p.probation.verify(); err != nil {
+		return err
+	}
+	return p.protected.verify()
+}
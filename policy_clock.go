@@ -0,0 +1,101 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+// clockPolicy implements PolicyClock. Its clock hand sweeps the shard's
+// linked list, which stays in insertion order since the policy keeps
+// accessed nodes in place, and each node's reference bit gives it a second
+// chance.
+type clockPolicy struct {
+	ns *NodeShards
+
+	// hand is the next node the clock hand inspects.
+	hand *Nodes
+}
+
+// inPlace implements inPlacePolicy.
+func (p *clockPolicy) inPlace() {}
+
+// OnInsert implements EvictionPolicy. New nodes start with a clear bit.
+func (p *clockPolicy) OnInsert(node *Nodes) {}
+
+// OnAccess implements EvictionPolicy, setting the node's reference bit.
+func (p *clockPolicy) OnAccess(node *Nodes) {
+	node.referenced = true
+}
+
+// OnEvict implements EvictionPolicy, moving the hand off the node before the
+// shard unlinks it.
+func (p *clockPolicy) OnEvict(node *Nodes) {
+	if p.hand == node {
+		p.hand = node.prev
+	}
+}
+
+// Victim implements EvictionPolicy. It sweeps the clock hand from the oldest
+// node towards the newest, wrapping around, and returns the first node whose
+// reference bit is clear. Referenced nodes passed on the way get a second
+// chance: their bit is cleared and they are only chosen on a later lap.
+// Pinned nodes are skipped. The hand stays on the returned node, and moves on
+// once the node is evicted.
+func (p *clockPolicy) Victim() *Nodes {
+	ns := p.ns
+	if ns.tail.prev == ns.head {
+		return nil
+	}
+	if p.hand == nil || p.hand == ns.head {
+		p.hand = ns.tail.prev
+	}
+
+	// Two laps clear every reference bit, so an unpinned node is found
+	// within them if there is one.
+	for steps := 0; steps <= 2*ns.size; steps++ {
+		if p.hand == ns.head {
+			p.hand = ns.tail.prev
+		}
+		node := p.hand
+		if !node.pinned {
+			if !node.referenced {
+				return node
+			}
+			node.referenced = false
+		}
+		p.hand = node.prev
+	}
+	return nil
+}
+
+// peek implements peekingPolicy. It follows the clock hand as Victim does,
+// without clearing reference bits: the first unreferenced node it passes,
+// or else the first node the hand would come back to once every bit is
+// cleared.
+func (p *clockPolicy) peek() *Nodes {
+	ns := p.ns
+	if ns.size == 0 || ns.tail.prev == ns.head {
+		return nil
+	}
+
+	node := p.hand
+	if node == nil || node == ns.head {
+		node = ns.tail.prev
+	}
+	var first *Nodes
+	for steps := 0; steps < ns.size; steps++ {
+		if node == ns.head {
+			node = ns.tail.prev
+		}
+		if !node.pinned {
+			if !node.referenced {
+				return node
+			}
+			if first == nil {
+				first = node
+			}
+		}
+		node = node.prev
+	}
+	return first
+}
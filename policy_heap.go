@@ -0,0 +1,130 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// heapPolicy implements the heap-ordered built-in policies PolicyLFU and
+// PolicyCost. Its nodes are kept in an EvictionHeap ordered by
+// evictsBefore, and the policies differ only in how an access changes the
+// fields that ordering compares.
+type heapPolicy struct {
+	heap EvictionHeap
+
+	// counting counts accesses (PolicyLFU), and byCost weighs nodes by
+	// their cost unless SetWeighted gave them a weight (PolicyCost).
+	counting, byCost bool
+
+	// bulk defers heap maintenance of OnEvict to endBulk.
+	bulk bool
+}
+
+// weigh sets the weight of the node under PolicyCost.
+func (p *heapPolicy) weigh(node *Nodes) {
+	if p.byCost && !node.weighted {
+		node.weight = node.nodeSize
+	}
+}
+
+// OnInsert implements EvictionPolicy, pushing the node onto the heap.
+func (p *heapPolicy) OnInsert(node *Nodes) {
+	p.weigh(node)
+	heap.Push(&p.heap, node)
+}
+
+// OnAccess implements EvictionPolicy, updating the node's ranking and its
+// position in the heap.
+func (p *heapPolicy) OnAccess(node *Nodes) {
+	if p.counting {
+		node.freq++
+	}
+	p.weigh(node)
+	if p.heap.holds(node) {
+		heap.Fix(&p.heap, node.heapIndex)
+	}
+}
+
+// OnEvict implements EvictionPolicy, removing the node from the heap, or
+// only marking it for endBulk during a bulk removal.
+func (p *heapPolicy) OnEvict(node *Nodes) {
+	if p.bulk {
+		node.heapIndex = -1
+		return
+	}
+	p.heap.RemoveNode(node)
+}
+
+// Victim implements EvictionPolicy, returning the root of the heap.
+func (p *heapPolicy) Victim() *Nodes {
+	if p.heap.Len() == 0 || p.heap[0].pinned {
+		return nil
+	}
+	return p.heap[0]
+}
+
+// peek implements peekingPolicy; Victim changes nothing.
+func (p *heapPolicy) peek() *Nodes {
+	return p.Victim()
+}
+
+// coldest implements orderedPolicy by sorting a copy of the heap.
+func (p *heapPolicy) coldest(n int, keep func(*Nodes) bool) []*Nodes {
+	ordered := append([]*Nodes(nil), p.heap...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return evictsBefore(ordered[i], ordered[j])
+	})
+
+	var nodes []*Nodes
+	for _, node := range ordered {
+		if len(nodes) == n {
+			break
+		}
+		if keep(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// beginBulk implements bulkPolicy.
+func (p *heapPolicy) beginBulk() {
+	p.bulk = true
+}
+
+// endBulk implements bulkPolicy, dropping the nodes evicted since beginBulk
+// from the heap and restoring its order in a single pass.
+func (p *heapPolicy) endBulk() {
+	p.bulk = false
+	p.heap.compact()
+}
+
+// tracks implements verifiedPolicy.
+func (p *heapPolicy) tracks(node *Nodes) bool {
+	return p.heap.holds(node)
+}
+
+// verify implements verifiedPolicy: the heap must hold exactly the shard's
+// nodes that can be evicted, each at its indexed position, in heap order.
+func (p *heapPolicy) verify(ns *NodeShards) error {
+	tracked := 0
+	for _, node := range ns.pool {
+		if node.noLRU || node.pinned {
+			continue
+		}
+		tracked++
+		if !p.heap.holds(node) {
+			return fmt.Errorf("node %q is not at its indexed heap position", node.Key)
+		}
+	}
+	if p.heap.Len() != tracked {
+		return fmt.Errorf("eviction heap holds %d nodes but pool holds %d", p.heap.Len(), tracked)
+	}
+	return p.heap.verify()
+}
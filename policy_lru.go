@@ -0,0 +1,38 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+// lruPolicy implements PolicyLRU on top of the shard's linked list, which the
+// shard already keeps in recency order, so it needs no state of its own.
+type lruPolicy struct {
+	ns *NodeShards
+}
+
+// OnInsert implements EvictionPolicy. The shard links the node at the head.
+func (p *lruPolicy) OnInsert(node *Nodes) {}
+
+// OnAccess implements EvictionPolicy. The shard moves the node to the head.
+func (p *lruPolicy) OnAccess(node *Nodes) {}
+
+// OnEvict implements EvictionPolicy. The shard unlinks the node.
+func (p *lruPolicy) OnEvict(node *Nodes) {}
+
+// Victim implements EvictionPolicy, returning the least recently used
+// unpinned node.
+func (p *lruPolicy) Victim() *Nodes {
+	ns := p.ns
+	for node := ns.tail.prev; node != ns.head; node = node.prev {
+		if !node.pinned {
+			return node
+		}
+	}
+	return nil
+}
+
+// peek implements peekingPolicy; Victim changes nothing.
+func (p *lruPolicy) peek() *Nodes {
+	return p.Victim()
+}
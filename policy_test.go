@@ -0,0 +1,267 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+)
+
+// hotKeysKept reads a small hot set repeatedly, then scans many keys read
+// once, and returns how many hot keys survive the scan.
+func hotKeysKept(t *testing.T, policy Policy) int {
+	t.Helper()
+	m := New(&Config{ShardCap: 1, NodeCap: 100, Policy: policy})
+	defer m.Close()
+
+	for i := 0; i < 20; i++ {
+		m.Set(fmt.Sprintf("hot%d", i), i, 1)
+	}
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 20; i++ {
+			m.Get(fmt.Sprintf("hot%d", i))
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		m.Set(fmt.Sprintf("scan%d", i), i, 1)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := 0
+	for i := 0; i < 20; i++ {
+		if _, ok := m.Peek(fmt.Sprintf("hot%d", i)); ok {
+			kept++
+		}
+	}
+	return kept
+}
+
+// TestLFUKeepsHotKeysUnderScan checks that LFU keeps a frequently read hot
+// set through a scan that flushes it out under LRU.
+func TestLFUKeepsHotKeysUnderScan(t *testing.T) {
+	if kept := hotKeysKept(t, PolicyLRU); kept != 0 {
+		t.Fatalf("LRU kept %d hot keys through the scan, want 0", kept)
+	}
+	if kept := hotKeysKept(t, PolicyLFU); kept != 20 {
+		t.Fatalf("LFU kept %d hot keys through the scan, want 20", kept)
+	}
+}
+
+// fifoPolicy is a custom EvictionPolicy that evicts in insertion order,
+// ignoring accesses.
+type fifoPolicy struct {
+	order    []*Nodes
+	accesses int
+}
+
+func (p *fifoPolicy) OnInsert(node *Nodes) { p.order = append(p.order, node) }
+
+func (p *fifoPolicy) OnAccess(node *Nodes) { p.accesses++ }
+
+func (p *fifoPolicy) OnEvict(node *Nodes) {
+	for i, n := range p.order {
+		if n == node {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *fifoPolicy) Victim() *Nodes {
+	if len(p.order) == 0 {
+		return nil
+	}
+	return p.order[0]
+}
+
+// TestCustomPolicy plugs a FIFO policy into the cache and checks that it,
+// rather than LRU, decides what is evicted.
+func TestCustomPolicy(t *testing.T) {
+	policy := &fifoPolicy{}
+	m := New(&Config{
+		ShardCap:     1,
+		NodeCap:      3,
+		CustomPolicy: func() EvictionPolicy { return policy },
+	})
+	defer m.Close()
+
+	m.Set("a", 1, 1)
+	m.Set("b", 2, 1)
+	m.Set("c", 3, 1)
+	m.Get("a")
+	m.Set("d", 4, 1)
+
+	if _, ok := m.Peek("a"); ok {
+		t.Fatal("a survived, want it evicted first despite its access")
+	}
+	for _, key := range []string{"b", "c", "d"} {
+		if _, ok := m.Peek(key); !ok {
+			t.Fatalf("%s was evicted, want it kept", key)
+		}
+	}
+	if policy.accesses != 1 {
+		t.Fatalf("policy saw %d accesses, want 1", policy.accesses)
+	}
+	if key, ok := m.NextVictim(0); !ok || key != "b" {
+		t.Fatalf("NextVictim(0) = %q, %v; want b, true", key, ok)
+	}
+
+	m.Pin("b")
+	m.Set("e", 5, 1)
+	if _, ok := m.Peek("b"); !ok {
+		t.Fatal("pinned b was evicted")
+	}
+	if _, ok := m.Peek("c"); ok {
+		t.Fatal("c survived, want it evicted in place of pinned b")
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPoliciesKeepInvariants runs a mixed workload under every built-in
+// policy and checks the shard invariants after it.
+func TestPoliciesKeepInvariants(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, Policy2Q, PolicyClock, PolicyLFU, PolicyCost} {
+		m := New(&Config{ShardCap: 2, NodeCap: 50, Policy: policy})
+		for i := 0; i < 500; i++ {
+			key := fmt.Sprintf("key%d", i%120)
+			switch i % 5 {
+			case 0, 1:
+				m.Set(key, i, uint64(1+i%7))
+			case 2:
+				m.Get(key)
+			case 3:
+				m.Pin(key)
+			case 4:
+				m.Unpin(fmt.Sprintf("key%d", (i+40)%120))
+			}
+			if i%50 == 0 {
+				m.RemovePrefix("key1")
+			}
+		}
+		if err := m.Verify(); err != nil {
+			t.Errorf("policy %d: %v", policy, err)
+		}
+		if orphans := m.Orphans(); len(orphans) > 0 {
+			t.Errorf("policy %d: Orphans() = %v", policy, orphans)
+		}
+		m.Close()
+	}
+}
+
+// TestTwoQAdmitsNewKeysOnceAllProtected promotes every entry of a full shard
+// and checks that new keys are still admitted, by demoting protected entries
+// rather than evicting each new key as soon as it is inserted.
+func TestTwoQAdmitsNewKeysOnceAllProtected(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 100, Policy: Policy2Q})
+	defer m.Close()
+
+	for i := 0; i < 100; i++ {
+		m.Set(fmt.Sprintf("old%d", i), i, 1)
+	}
+	for i := 0; i < 100; i++ {
+		m.Get(fmt.Sprintf("old%d", i))
+	}
+
+	admitted := 0
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("new%d", i)
+		m.Set(key, i, 1)
+		if _, ok := m.Peek(key); ok {
+			admitted++
+		}
+	}
+	if admitted != 100 {
+		t.Fatalf("%d of 100 new keys admitted, want 100", admitted)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTwoQKeepsWorkingSetUnderScan checks that 2Q keeps a working set read
+// twice through a scan that flushes it out under LRU, as long as the working
+// set fits in the protected segment.
+func TestTwoQKeepsWorkingSetUnderScan(t *testing.T) {
+	if kept := hotKeysKept(t, Policy2Q); kept != 20 {
+		t.Fatalf("2Q kept %d hot keys through the scan, want 20", kept)
+	}
+}
+
+// TestTiesEvictFirstInserted writes entries that tie on every ranking field
+// under the heap-ordered policies, and checks that they are evicted in
+// insertion order.
+func TestTiesEvictFirstInserted(t *testing.T) {
+	for _, policy := range []Policy{PolicyLFU, PolicyCost} {
+		m := New(&Config{ShardCap: 1, NodeCap: 4, Policy: policy})
+		for i := 0; i < 4; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, 1)
+		}
+		// Give every entry the same last use, so that only insertion
+		// order tells them apart.
+		for i := 0; i < 4; i++ {
+			withNode(t, m, fmt.Sprintf("key%d", i), func(node *Nodes) { node.lastUsed = 1 })
+		}
+		m.pool[0].evictor.(*heapPolicy).heap.compact()
+
+		for i := 4; i < 6; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, 1)
+		}
+		for i := 0; i < 2; i++ {
+			if _, ok := m.Peek(fmt.Sprintf("key%d", i)); ok {
+				t.Errorf("policy %d: key%d survived, want the first inserted evicted first", policy, i)
+			}
+		}
+		for i := 2; i < 6; i++ {
+			if _, ok := m.Peek(fmt.Sprintf("key%d", i)); !ok {
+				t.Errorf("policy %d: key%d was evicted", policy, i)
+			}
+		}
+		m.Close()
+	}
+}
+
+// TestHeapRemoveAfterSwaps reorders an LFU shard's heap through reads, then
+// removes keys one by one and checks that each removal takes exactly its own
+// node out of the heap, with every remaining node still at its indexed
+// position.
+func TestHeapRemoveAfterSwaps(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 50, Policy: PolicyLFU})
+	defer m.Close()
+	for i := 0; i < 40; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	for i := 39; i >= 0; i-- {
+		for j := 0; j < (i*7)%11; j++ {
+			m.Get(fmt.Sprintf("key%d", i))
+		}
+	}
+
+	shard := m.pool[0]
+	hp := shard.evictor.(*heapPolicy)
+	for i := 0; i < 40; i += 3 {
+		key := fmt.Sprintf("key%d", i)
+		node := shard.pool[key]
+		m.Remove(key)
+		if hp.heap.holds(node) || node.heapIndex != -1 {
+			t.Fatalf("%s still in the heap at index %d after Remove", key, node.heapIndex)
+		}
+		if len(hp.heap) != len(shard.pool) {
+			t.Fatalf("heap holds %d nodes for %d entries after removing %s", len(hp.heap), len(shard.pool), key)
+		}
+		for j, n := range hp.heap {
+			if n.heapIndex != j {
+				t.Fatalf("%s at heap position %d has index %d", n.Key, j, n.heapIndex)
+			}
+		}
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
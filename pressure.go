@@ -0,0 +1,59 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"runtime"
+	"time"
+)
+
+// EvictToSize evicts the coldest entries of the whole cache, in the order the
+// shards' eviction policies pick them, until the total cost is at most size
+// or nothing more can be evicted. Pinned and pure TTL entries are never
+// evicted. It returns the number of entries evicted.
+func (m *CacheManager) EvictToSize(size uint64) int {
+	evicted := 0
+	for m.cost.Load() > size {
+		if !m.evictColdest() {
+			break
+		}
+		evicted++
+	}
+	return evicted
+}
+
+// heapAlloc returns the number of bytes of allocated heap objects.
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// watchMemory samples heapInUse every interval and, while it exceeds
+// threshold, shrinks the cache's cost by the excess, until the manager's done
+// channel is closed.
+func (m *CacheManager) watchMemory(interval time.Duration, threshold uint64, heapInUse func() uint64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			inUse := heapInUse()
+			if inUse <= threshold {
+				continue
+			}
+			excess := inUse - threshold
+			var target uint64
+			if cost := m.cost.Load(); cost > excess {
+				target = cost - excess
+			}
+			m.EvictToSize(target)
+		case <-m.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMemoryPressure drives the memory pressure watcher through a HeapInUse
+// that reports the cache's cost on top of a base, and checks that it evicts
+// the coldest entries by the excess over the threshold, and nothing while
+// the heap stays below it.
+func TestMemoryPressure(t *testing.T) {
+	var base atomic.Uint64
+	var cache atomic.Pointer[CacheManager]
+	m := New(&Config{
+		ShardCap:                1,
+		NodeCap:                 20,
+		MemoryPressureThreshold: 1000,
+		MemoryPressureInterval:  time.Millisecond,
+		HeapInUse: func() uint64 {
+			if m := cache.Load(); m != nil {
+				return base.Load() + m.Cost()
+			}
+			return 0
+		},
+	})
+	defer m.Close()
+	cache.Store(m)
+	for i := 0; i < 10; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 10)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if c := m.Cost(); c != 100 {
+		t.Fatalf("Cost() = %d below the threshold, want 100", c)
+	}
+
+	base.Store(930)
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Cost() > 70 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if c := m.Cost(); c != 70 {
+		t.Fatalf("Cost() = %d under 30 bytes of pressure, want 70", c)
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := m.Peek(fmt.Sprintf("key%d", i)); ok != (i >= 3) {
+			t.Errorf("Peek(key%d) = %v, want only key0 to key2 evicted", i, ok)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetReadOnly stores val under key as a read-only entry that never expires,
+// for values that stay constant for the lifetime of the process, such as
+// loaded configuration. Later writes of key through Set, SetTTL, SetTTLNoLRU,
+// SetWithCost, SetMany, Merge, GetSet, Rename or a transaction leave the
+// entry as it is; SetChecked, Tx.Set and Rename report them with ErrReadOnly.
+// The entry can still be read, removed, and evicted like any other.
+// SetReadOnly returns ErrReadOnly if key already holds a read-only entry, and
+// ErrCostExceeded if StrictCost cannot make room for it. An
+// entry whose size exceeds MaxCost is rejected, and SetReadOnly does nothing
+// while the cache is draining.
+func (m *CacheManager) SetReadOnly(key string, val interface{}, size uint64) error {
+	if m.draining.Load() {
+		return nil
+	}
+	m.dropPending(key)
+	m.logOp(OpSet, key, false)
+	size = m.costOf(val, size)
+	val, size = m.encode(key, val, size)
+	if m.oversized(size) {
+		return m.discard(key)
+	}
+	m.reserveFor(key, size)
+
+	shard := m.lockKey(key)
+	defer m.unlockKey(shard)
+
+	if err := m.storeLocked(shard, key, val, size, 0, 0, time.Now().Unix(), nil); err != nil {
+		return err
+	}
+	if node, exists := shard.pool[key]; exists {
+		node.readOnly = true
+	}
+	return nil
+}
+
+// SetChecked behaves like Set, but reports the writes Set drops silently: it
+// returns ErrReadOnly if key holds a read-only entry stored by SetReadOnly,
+// ErrCostExceeded if StrictCost cannot make room for the write, and
+// ErrTTLRequired, instead of panicking, when RequireExplicitTTL is set and
+// no TTLRule matches key. SetChecked is never coalesced, and discards a write
+// of key still buffered by WriteCoalesceWindow.
+func (m *CacheManager) SetChecked(key string, val interface{}, size uint64) error {
+	if m.requireTTL {
+		if _, ok := m.ruleTTL(key); !ok {
+			return fmt.Errorf("%w: SetChecked(%q)", ErrTTLRequired, key)
+		}
+	}
+	m.dropPending(key)
+	return m.setDefault(key, val, size, nil)
+}
+
+// readOnly reports whether key holds a live read-only entry.
+func (m *CacheManager) readOnly(key string) bool {
+	shard := m.rlockKey(key)
+	defer m.runlockKey(shard)
+
+	node, ok := shard.pool[key]
+	return ok && node.readOnly && !shard.stale(node, time.Now().Unix())
+}
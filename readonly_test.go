@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSetReadOnly checks that a read-only entry never expires, that Set and
+// SetTTL leave it as it is while SetChecked and SetReadOnly report
+// ErrReadOnly, and that it can still be removed.
+func TestSetReadOnly(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	if err := m.SetReadOnly("config", "v1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, ok := m.TTL("config"); !ok || ttl != NoExpiry {
+		t.Fatalf("TTL(config) = %v, %v; want NoExpiry", ttl, ok)
+	}
+
+	m.Set("config", "v2", 1)
+	m.SetTTL("config", "v3", 1, time.Minute)
+	if err := m.SetChecked("config", "v4", 1); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SetChecked(config) = %v, want ErrReadOnly", err)
+	}
+	if err := m.SetReadOnly("config", "v5", 1); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SetReadOnly(config) = %v on a read-only entry, want ErrReadOnly", err)
+	}
+	if v := m.Get("config"); v != "v1" {
+		t.Fatalf("Get(config) = %v after the writes, want v1", v)
+	}
+
+	m.Remove("config")
+	if err := m.SetChecked("config", "v6", 1); err != nil {
+		t.Fatalf("SetChecked(config) = %v after Remove, want nil", err)
+	}
+	if v := m.Get("config"); v != "v6" {
+		t.Fatalf("Get(config) = %v, want v6", v)
+	}
+}
+
+// TestRenameOntoReadOnly checks that Rename refuses to overwrite a read-only
+// entry, within a shard and across shards, leaving both entries in place.
+func TestRenameOntoReadOnly(t *testing.T) {
+	// Keys starting with "a" live in shard 0, the others in shard 1.
+	m := New(&Config{ShardCap: 2, NodeCap: 10, ShardFunc: func(key string, n int) int {
+		if key[0] == 'a' {
+			return 0
+		}
+		return 1
+	}})
+	defer m.Close()
+	m.SetReadOnly("a-config", "fixed", 1)
+	m.SetReadOnly("b-config", "fixed", 1)
+
+	for _, src := range []string{"a-temp", "b-temp"} {
+		m.Set(src, "moving", 1)
+		for _, dst := range []string{"a-config", "b-config"} {
+			found, err := m.Rename(src, dst)
+			if !found || !errors.Is(err, ErrReadOnly) {
+				t.Errorf("Rename(%s, %s) = %v, %v; want true, ErrReadOnly", src, dst, found, err)
+			}
+			if v, _ := m.Peek(dst); v != "fixed" {
+				t.Errorf("Peek(%s) = %v after Rename, want fixed", dst, v)
+			}
+			if v, _ := m.Peek(src); v != "moving" {
+				t.Errorf("Peek(%s) = %v after Rename, want moving", src, v)
+			}
+		}
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSetCheckedRequireExplicitTTL checks that SetChecked returns
+// ErrTTLRequired rather than panicking for a key no TTLRule covers.
+func TestSetCheckedRequireExplicitTTL(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, RequireExplicitTTL: true})
+	defer m.Close()
+	if err := m.SetChecked("key", 1, 1); !errors.Is(err, ErrTTLRequired) {
+		t.Fatalf("SetChecked(key) = %v, want ErrTTLRequired", err)
+	}
+	if _, ok := m.Peek("key"); ok {
+		t.Fatal("key was stored")
+	}
+}
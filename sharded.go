@@ -0,0 +1,44 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"strings"
+	"time"
+)
+
+// shardKeyEscaper escapes the characters of a shard key that would otherwise
+// let it run into the cache key.
+var shardKeyEscaper = strings.NewReplacer(`\`, `\\`, "}", `\}`)
+
+// ShardedKey returns the key under which SetSharded stores cacheKey: cacheKey
+// prefixed with shardKey as its hash tag, so that every key built with the
+// same shardKey lives in the same shard. The result can be passed to any
+// other method, such as Remove or TTL, to reach the entry. Backslashes and
+// '}' in shardKey are escaped with a backslash, so that distinct pairs of
+// shardKey and cacheKey never build the same key; the hash tag of such a
+// shardKey ends at its first '}', which still keeps its keys together. An
+// empty shardKey is not a hash tag, and the whole key is hashed instead.
+func ShardedKey(shardKey, cacheKey string) string {
+	return "{" + shardKeyEscaper.Replace(shardKey) + "}" + cacheKey
+}
+
+// SetSharded stores val under cacheKey for ttl, like SetTTL, but places it in
+// the shard chosen by shardKey rather than by cacheKey, so that related keys,
+// such as all the keys of one tenant, share a shard. This generalizes hash
+// tags: the entry is stored under ShardedKey(shardKey, cacheKey), so entries
+// with the same cacheKey but different shardKeys are distinct, and the entry
+// keeps its shard through rebalancing and Rehash. A ttl of zero or less makes
+// the entry never expire.
+func (m *CacheManager) SetSharded(shardKey, cacheKey string, val interface{}, size uint64, ttl time.Duration) {
+	m.SetTTL(ShardedKey(shardKey, cacheKey), val, size, ttl)
+}
+
+// GetSharded retrieves the value stored by SetSharded under shardKey and
+// cacheKey. The boolean reports whether the entry was present and unexpired.
+func (m *CacheManager) GetSharded(shardKey, cacheKey string) (interface{}, bool) {
+	return m.GetOK(ShardedKey(shardKey, cacheKey))
+}
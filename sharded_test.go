@@ -0,0 +1,61 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestShardedKeyUnambiguous stores pairs of shard and cache keys that would
+// build the same key if the shard key were not escaped, and checks that
+// each pair keeps its own entry.
+func TestShardedKeyUnambiguous(t *testing.T) {
+	pairs := [][2]string{
+		{"a}b", "c"},
+		{"a", "b}c"},
+		{`a\`, "}c"},
+		{`a\}`, "c"},
+		{"a", `\}c`},
+		{"", "c"},
+	}
+	m := New(&Config{ShardCap: 4, NodeCap: 100})
+	defer m.Close()
+
+	keys := make(map[string][2]string)
+	for i, p := range pairs {
+		key := ShardedKey(p[0], p[1])
+		if other, ok := keys[key]; ok {
+			t.Fatalf("ShardedKey(%q, %q) = ShardedKey(%q, %q) = %q", p[0], p[1], other[0], other[1], key)
+		}
+		keys[key] = p
+		m.SetSharded(p[0], p[1], i, 1, time.Hour)
+	}
+	for i, p := range pairs {
+		if v, ok := m.GetSharded(p[0], p[1]); !ok || v != i {
+			t.Errorf("GetSharded(%q, %q) = %v, %v; want %d, true", p[0], p[1], v, ok, i)
+		}
+	}
+}
+
+// TestSetShardedColocates checks that the keys stored under one shard key
+// share a shard, including a shard key that needs escaping.
+func TestSetShardedColocates(t *testing.T) {
+	m := New(&Config{ShardCap: 16, NodeCap: 100})
+	defer m.Close()
+
+	for _, shardKey := range []string{"tenant:7", `ten}ant\7`} {
+		m.poolMut.RLock()
+		want := m.shardIndex(ShardedKey(shardKey, "key0"))
+		for i := 1; i < 50; i++ {
+			if got := m.shardIndex(ShardedKey(shardKey, fmt.Sprintf("key%d", i))); got != want {
+				t.Errorf("shard key %q: key%d in shard %d, want %d", shardKey, i, got, want)
+			}
+		}
+		m.poolMut.RUnlock()
+	}
+}
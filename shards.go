@@ -6,8 +6,9 @@
 package cerebru
 
 import (
-	"container/heap"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,7 +21,7 @@ type NodeShards struct {
 
 	// mut is a read-write mutex used to synchronize access to the shard.
 	// It allows multiple readers or a single writer to access the shard concurrently.
-	mut sync.RWMutex
+	mut shardMutex
 
 	// head is a pointer to the first node in the linked list of nodes within this shard.
 	head *Nodes
@@ -30,70 +31,589 @@ type NodeShards struct {
 
 	capacity, size int
 
-	// evictionHeap is a min-heap that manages the eviction of nodes based on their
-	// last used timestamps, facilitating LRU or LFU eviction policies.
-	evictionHeap *EvictionHeap
+	// hysteresis is the fraction of capacity freed at once when a write
+	// overflows it; see lowWater.
+	hysteresis float64
+
+	// jitter is the fraction by which spread varies the lifetime of written
+	// entries, drawing from rng. rng is only used under the write lock.
+	jitter float64
+	rng    *rand.Rand
+
+	// cleanerBudget is the maximum number of expired nodes removed per
+	// cleaner sweep. Zero means no limit.
+	cleanerBudget int
+
+	// cleanerYield makes the cleaner skip sweeps while the shard is under
+	// write pressure. writes counts the writes made to the shard; it is read
+	// by the cleaner without holding the lock.
+	cleanerYield bool
+	writes       atomic.Uint64
 
 	// cleanerStop is a channel used to signal the stopping of background cleaning processes
 	// that may be running to remove expired or unused nodes from the shard.
 	cleanerStop chan struct{}
 
-	// nodeIndex is a map that associates each node with its index in the pool,
-	// allowing for efficient lookups and removals.
-	nodeIndex map[*Nodes]int
+	// ownCleaner reports whether the shard runs its own cleaner goroutine.
+	// Shards without one are swept by the manager's shared cleaner.
+	ownCleaner bool
 
+	// shardSize is the total cost of the nodes held by this shard.
 	shardSize uint64
+
+	// maxCost is the CacheManager's cost budget, which no single shard
+	// may exceed on its own.
+	maxCost uint64
+
+	// cost points to the CacheManager's total cost across all shards,
+	// kept up to date by grow and shrink.
+	cost *atomic.Uint64
+
+	// poolMut points to the owning CacheManager's pool lock. The cleaner,
+	// which locks the shard without going through the manager, read-locks
+	// it first, so that dynamic sharding never sees the shard mid-update.
+	poolMut *sync.RWMutex
+
+	// strict makes MaxCost a hard cap on cost: writes charge the growth
+	// they need before growing the shard, and cost released by shrink is
+	// held back as credit, spent by later grows under the same lock, until
+	// settle gives the rest back when the lock is released. A write that
+	// replaces a node can then reuse its cost without another shard taking
+	// it in between.
+	strict bool
+	credit uint64
+
+	// release, if set, is called whenever a node leaves the shard so that
+	// callers waiting for free capacity can be woken up.
+	release func()
+
+	// dispose, if set, receives the nodes removed from the shard once its
+	// lock has been released. pending queues them while the lock is held.
+	dispose func([]removal)
+	pending []removal
+
+	// evictor chooses the node to evict. newEvictor makes it, and is
+	// called again by reset for a fresh one. inPlace caches whether it
+	// implements inPlacePolicy.
+	evictor    EvictionPolicy
+	newEvictor func(ns *NodeShards) EvictionPolicy
+	inPlace    bool
+
+	// seq is the last insertion sequence number handed out to a node.
+	seq uint64
+
+	// epoch points to the owning CacheManager's generation counter.
+	// Nodes stamped with an older epoch are treated as stale.
+	epoch *atomic.Uint64
+
+	// stats points to the counters shared with the owning CacheManager.
+	stats *cacheStats
+
+	// trackHold makes lock and unlock measure how long the write lock is
+	// held. lockedAt is when it was last taken, and maxHold the longest
+	// hold seen, in nanoseconds.
+	trackHold bool
+	lockedAt  time.Time
+	maxHold   atomic.Int64
 }
 
 // addToHead adds a node to the head of the linked list in the NodeShards.
-// It updates the node's previous and next pointers, sets the last used timestamp,
-// and pushes the node onto the eviction heap.
+// It updates the node's previous and next pointers and sets the last used
+// timestamp (and the creation timestamp and insertion sequence of a new
+// node). Nodes kept out of the LRU only get their timestamps updated.
 func (ns *NodeShards) addToHead(node *Nodes) {
 	now := time.Now().Unix()
+	node.lastUsed = now
+	if node.createdAt == 0 {
+		node.createdAt = now
+	}
+	if node.seq == 0 {
+		ns.seq++
+		node.seq = ns.seq
+	}
+	if node.noLRU {
+		return
+	}
+
 	node.prev = ns.head
 	nextNode := ns.head.next
 	node.next = nextNode
 	nextNode.prev = node
 	ns.head.next = node
-	node.lastUsed = now
-	heap.Push(ns.evictionHeap, node)
-	ns.nodeIndex[node] = ns.evictionHeap.Len() - 1
 }
 
-// moveToHead moves a node to the head of the linked list.
-// It first removes the node from its current position and then adds it to the head.
+// moveToHead records an access to a node: it moves the node to the head of
+// the linked list, unless the eviction policy keeps accessed nodes in place,
+// and reports the access to the policy.
 func (ns *NodeShards) moveToHead(node *Nodes) {
-	ns.removeNode(node)
-	ns.addToHead(node)
+	if ns.inPlace || node.noLRU {
+		node.lastUsed = time.Now().Unix()
+	} else {
+		ns.removeFromList(node)
+		ns.addToHead(node)
+	}
+	if !node.noLRU && !node.pinned {
+		ns.evictor.OnAccess(node)
+	}
 }
 
-// removeNode removes a node from the linked list and the eviction heap.
-// It updates the node index and decreases the size of the NodeShards.
+// removeNode removes a node from the linked list and the eviction policy.
+// Nodes kept out of the LRU are in neither, so nothing is done for them.
 func (ns *NodeShards) removeNode(node *Nodes) {
-	ns.removeFromTail(node)
-	heap.Remove(ns.evictionHeap, ns.nodeIndex[node])
-	delete(ns.nodeIndex, node)
+	if node.noLRU {
+		return
+	}
+	ns.untrack(node)
+	ns.removeFromList(node)
+}
+
+// track hands a node that can be evicted to the eviction policy. Pinned nodes
+// and nodes kept out of the LRU are not.
+func (ns *NodeShards) track(node *Nodes) {
+	if !node.noLRU && !node.pinned {
+		ns.evictor.OnInsert(node)
+	}
+}
+
+// untrack takes a node handed to the eviction policy by track back from it.
+func (ns *NodeShards) untrack(node *Nodes) {
+	if !node.noLRU && !node.pinned {
+		ns.evictor.OnEvict(node)
+	}
+}
+
+// stamp marks a node as written in the cache's current epoch and counts the
+// write towards the shard's write rate.
+func (ns *NodeShards) stamp(node *Nodes) {
+	ns.writes.Add(1)
+	if ns.epoch != nil {
+		node.epoch = ns.epoch.Load()
+	}
+}
+
+// insert adds a node to the pool, the head of the linked list and the eviction
+// policy, stamps it with the current epoch, and updates the size accounting of
+// the NodeShards. It does not enforce capacity; callers evict afterwards as
+// needed.
+func (ns *NodeShards) insert(node *Nodes) {
+	ns.stamp(node)
+	ns.grow(node.nodeSize)
+	ns.addToHead(node)
+	ns.pool[node.Key] = node
+	ns.size++
+	ns.track(node)
+}
+
+// grow adds n to the cost held by the shard and by the whole cache. Under
+// strict cost accounting, the shard's credit is spent first.
+func (ns *NodeShards) grow(n uint64) {
+	ns.shardSize += n
+	if ns.cost == nil {
+		return
+	}
+	spent := min(n, ns.credit)
+	ns.credit -= spent
+	if n > spent {
+		ns.cost.Add(n - spent)
+	}
+}
+
+// shrink subtracts n from the cost held by the shard and by the whole cache.
+// Under strict cost accounting, the cache's cost is kept as the shard's
+// credit until settle.
+func (ns *NodeShards) shrink(n uint64) {
+	ns.shardSize -= n
+	if ns.cost == nil {
+		return
+	}
+	if ns.strict {
+		ns.credit += n
+		return
+	}
+	ns.cost.Add(^(n - 1))
+}
+
+// charge makes sure the shard holds n units of credit for a grow under
+// strict cost accounting, reserving what its credit lacks from the cache's
+// cost with a compare-and-swap. It returns false, reserving nothing, if that
+// would take the cache over MaxCost.
+func (ns *NodeShards) charge(n uint64) bool {
+	if ns.cost == nil || ns.credit >= n {
+		return true
+	}
+	need := n - ns.credit
+	for {
+		c := ns.cost.Load()
+		if c+need > ns.maxCost {
+			return false
+		}
+		if ns.cost.CompareAndSwap(c, c+need) {
+			ns.credit += need
+			return true
+		}
+	}
+}
+
+// settle gives the shard's unspent credit back to the cache's cost. It is
+// called before the write lock is released.
+func (ns *NodeShards) settle() {
+	if ns.credit > 0 {
+		ns.cost.Add(^(ns.credit - 1))
+		ns.credit = 0
+	}
+}
+
+// handOver moves up to n of the shard's credit to dst, for a node moving
+// from the shard to dst, so that the node's cost is not released to other
+// shards in between.
+func (ns *NodeShards) handOver(dst *NodeShards, n uint64) {
+	moved := min(n, ns.credit)
+	ns.credit -= moved
+	dst.credit += moved
+}
+
+// makeRoom charges n under strict cost accounting, evicting the shard's
+// nodes until the charge fits within MaxCost. keep, if not nil, is a node
+// about to be replaced, which is spared. It returns false once nothing more
+// can be evicted.
+func (ns *NodeShards) makeRoom(n uint64, keep *Nodes) bool {
+	if keep != nil && !keep.pinned {
+		ns.untrack(keep)
+		keep.pinned = true
+		defer func() {
+			keep.pinned = false
+			ns.track(keep)
+		}()
+	}
+	for !ns.charge(n) {
+		if ns.evict() == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// fits reports whether node can grow to size. Under strict cost accounting,
+// room for the growth is made and charged, to be spent by the update that
+// follows; if that fails, fits returns false and node must be left as it is.
+func (ns *NodeShards) fits(node *Nodes, size uint64) bool {
+	return !ns.strict || size <= node.nodeSize || ns.makeRoom(size-node.nodeSize, node)
+}
+
+// update replaces the value and size of a node already in the shard, stamps it
+// and promotes it, then evicts older nodes if the shard is over its cost budget.
+// The node keeps its expiry.
+func (ns *NodeShards) update(node *Nodes, val interface{}, size uint64) {
+	ns.shrink(node.nodeSize)
+	node.Value = val
+	node.nodeSize = size
+	ns.grow(size)
+	ns.stamp(node)
+	ns.moveToHead(node)
+	ns.evictOverCost()
+}
+
+// admit inserts a node and enforces the shard's capacity and cost budget.
+// Normally the node is inserted first and older nodes are evicted afterwards.
+// Under strict cost accounting, room is made before inserting, and the
+// node's cost charged against MaxCost, so that neither is ever exceeded; if
+// pinned nodes prevent that, the node is not inserted and admit returns false.
+func (ns *NodeShards) admit(node *Nodes) bool {
+	if ns.strict {
+		if ns.size >= ns.capacity {
+			ns.evictTo(ns.lowWater() - 1)
+		}
+		for ns.size >= ns.capacity {
+			if ns.evict() == nil {
+				return false
+			}
+		}
+		if !ns.makeRoom(node.nodeSize, nil) {
+			return false
+		}
+		ns.insert(node)
+		return true
+	}
+
+	ns.insert(node)
+	ns.evictOverCapacity()
+	ns.evictOverCost()
+	return true
+}
+
+// evictOverCapacity evicts nodes once a write has taken the shard over its
+// capacity, bringing it down to lowWater rather than just back to capacity.
+func (ns *NodeShards) evictOverCapacity() {
+	if ns.size > ns.capacity {
+		ns.evictTo(ns.lowWater())
+	}
+}
+
+// lowWater returns the number of nodes the shard is brought down to when it
+// overflows: its capacity less the hysteresis fraction of it, but at least one.
+func (ns *NodeShards) lowWater() int {
+	if ns.hysteresis <= 0 {
+		return ns.capacity
+	}
+	low := ns.capacity - int(float64(ns.capacity)*ns.hysteresis)
+	if low < 1 {
+		low = 1
+	}
+	return low
 }
 
-// removeTail removes the last node from the linked list and returns it.
-// It calls removeNode to handle the removal process.
-func (ns *NodeShards) removeTail() *Nodes {
-	node := ns.tail.prev
+// evictTo evicts nodes until the shard holds at most n, or no more can be evicted.
+func (ns *NodeShards) evictTo(n int) {
+	for ns.size > n {
+		if ns.evict() == nil {
+			return
+		}
+	}
+}
+
+// unlink removes a node from the linked list, the eviction policy and the
+// pool, and updates the size accounting of the NodeShards. The node itself
+// is left intact so that it can be inserted elsewhere.
+func (ns *NodeShards) unlink(node *Nodes) {
 	ns.removeNode(node)
+	delete(ns.pool, node.Key)
+	ns.size--
+	ns.shrink(node.nodeSize)
+	if ns.release != nil {
+		ns.release()
+	}
+}
+
+// deleteNode unlinks a node that is leaving the cache and records it, with
+// the reason it left, for the release hooks.
+func (ns *NodeShards) deleteNode(node *Nodes, reason removalReason) {
+	ns.unlink(node)
+	ns.record(node, reason)
+}
+
+// removeBulk removes many nodes from the shard. They are unlinked like any
+// other node, but eviction policies that implement bulkPolicy, such as the
+// heap-ordered ones, defer their maintenance to a single pass once all of
+// them are gone, which keeps bulk removal linear in the size of the shard.
+func (ns *NodeShards) removeBulk(nodes []*Nodes) {
+	if len(nodes) == 0 {
+		return
+	}
+
+	bulk, _ := ns.evictor.(bulkPolicy)
+	if bulk != nil {
+		bulk.beginBulk()
+	}
+	for _, node := range nodes {
+		ns.record(node, removalRemoved)
+		ns.removeNode(node)
+		delete(ns.pool, node.Key)
+		ns.size--
+		ns.shrink(node.nodeSize)
+	}
+	if bulk != nil {
+		bulk.endBulk()
+	}
+
+	if ns.release != nil {
+		ns.release()
+	}
+}
+
+// reset empties the shard: its pool and linked list are cleared, its eviction
+// policy replaced by a fresh one and its size accounting zeroed. The removed
+// nodes are neither recorded nor released; callers that discard them do so
+// themselves.
+func (ns *NodeShards) reset() {
+	ns.pool = make(map[string]*Nodes, len(ns.pool))
+	ns.head.next = ns.tail
+	ns.tail.prev = ns.head
+	ns.setEvictor()
+	ns.size = 0
+	ns.shrink(ns.shardSize)
+}
+
+// setEvictor gives the shard a fresh eviction policy made by newEvictor.
+func (ns *NodeShards) setEvictor() {
+	ns.evictor = ns.newEvictor(ns)
+	_, ns.inPlace = ns.evictor.(inPlacePolicy)
+}
+
+// clear removes every node from the shard, recording each one for the release
+// hooks with reason, and returns how many were removed.
+func (ns *NodeShards) clear(reason removalReason) int {
+	for _, node := range ns.pool {
+		ns.record(node, reason)
+	}
+	removed := ns.size
+	ns.reset()
+	return removed
+}
+
+// stale reports whether a node should no longer be served: either it has
+// expired by now, or it was written before the cache's epoch was last bumped.
+func (ns *NodeShards) stale(node *Nodes, now int64) bool {
+	if node.expired(now) {
+		return true
+	}
+	return ns.epoch != nil && node.epoch != ns.epoch.Load()
+}
+
+// lookup returns the live node stored under key. A stale node is removed
+// from the shard and reported as missing.
+func (ns *NodeShards) lookup(key string, now int64) (*Nodes, bool) {
+	node, exists := ns.pool[key]
+	if !exists {
+		return nil, false
+	}
+	if ns.stale(node, now) {
+		ns.deleteNode(node, removalExpired)
+		return nil, false
+	}
+	return node, true
+}
+
+// victim returns the node the shard's eviction policy would evict next.
+// Pinned nodes, pure TTL nodes and nodes no longer in the shard are never
+// chosen, whatever the policy returns. It returns nil if no node can be
+// evicted.
+func (ns *NodeShards) victim() *Nodes {
+	if ns.size == 0 {
+		return nil
+	}
+	node := ns.evictor.Victim()
+	if node == nil || node.pinned || node.noLRU || ns.pool[node.Key] != node {
+		return nil
+	}
 	return node
 }
 
+// spread randomly moves expiry, an expiry timestamp set at now, by up to the
+// shard's jitter fraction of the lifetime it gives, keeping it after now.
+// Entries that never expire are left alone. The caller must hold the shard
+// lock.
+func (ns *NodeShards) spread(expiry, now int64) int64 {
+	if ns.jitter <= 0 || expiry <= now {
+		return expiry
+	}
+	life := float64(expiry - now)
+	expiry += int64(life * ns.jitter * (2*ns.rng.Float64() - 1))
+	if expiry <= now {
+		expiry = now + 1
+	}
+	return expiry
+}
+
+// peekVictim returns the node evict would remove next without changing any
+// state, so that it can be called under a read lock, if the eviction policy
+// implements peekingPolicy. Other policies are asked through victim, which
+// needs the write lock. Like victim, it never returns pinned or pure TTL
+// nodes, nor nodes no longer in the shard.
+func (ns *NodeShards) peekVictim() *Nodes {
+	p, ok := ns.evictor.(peekingPolicy)
+	if !ok {
+		return ns.victim()
+	}
+	if ns.size == 0 {
+		return nil
+	}
+	node := p.peek()
+	if node == nil || node.pinned || node.noLRU || ns.pool[node.Key] != node {
+		return nil
+	}
+	return node
+}
+
+// coldest returns up to n live, unpinned nodes of the shard in the order its
+// eviction policy would evict them, without changing any state. Policies that
+// do not implement orderedPolicy, such as PolicyLRU and PolicyClock, whose
+// reference bits are ignored, are taken to evict in list order from the
+// oldest node. The caller must hold the shard lock.
+func (ns *NodeShards) coldest(n int, now int64) []*Nodes {
+	keep := func(node *Nodes) bool {
+		return !node.pinned && !ns.stale(node, now)
+	}
+	if p, ok := ns.evictor.(orderedPolicy); ok {
+		return p.coldest(n, keep)
+	}
+
+	var nodes []*Nodes
+	for node := ns.tail.prev; node != ns.head && len(nodes) < n; node = node.prev {
+		if keep(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// evict removes the node chosen by the shard's eviction policy and returns it.
+// It returns nil if no node can be evicted.
+func (ns *NodeShards) evict() *Nodes {
+	node := ns.victim()
+	if node == nil {
+		return nil
+	}
+	ns.deleteNode(node, removalEvicted)
+	if ns.stats != nil {
+		ns.stats.recordEviction()
+	}
+	return node
+}
+
+// evictOverCost evicts least recently used nodes while the shard holds more
+// than its cost budget. The most recently used node and pinned nodes are always kept.
+func (ns *NodeShards) evictOverCost() {
+	for ns.shardSize > ns.maxCost && ns.size > 1 {
+		if ns.evict() == nil {
+			break
+		}
+	}
+}
+
+// cleanerYieldRate is the write rate, in writes per second, above which a
+// yielding cleaner considers its shard under load.
+const cleanerYieldRate = 1000
+
+// cleanerMaxYields is the number of consecutive sweeps a yielding cleaner may
+// skip before it sweeps regardless of the write rate.
+const cleanerMaxYields = 3
+
+// yieldSweep reports whether a yielding cleaner skips its sweep, given the
+// writes made to the shard over the elapsed time since the previous tick and
+// the number of sweeps skipped in a row so far.
+func (s *NodeShards) yieldSweep(writes uint64, elapsed time.Duration, yields int) bool {
+	rate := float64(writes) / elapsed.Seconds()
+	return s.cleanerYield && rate > cleanerYieldRate && yields < cleanerMaxYields
+}
+
 // startCleaner starts a background cleaner that periodically checks for expired nodes.
 // It adjusts the cleaning interval based on the number of expired nodes found.
+// If the shard yields under load, a sweep is skipped while the shard has been
+// written at more than cleanerYieldRate since the previous tick.
 func (s *NodeShards) startCleaner() {
 	baseInterval := time.Second * 5
 	interval := baseInterval
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	lastTick := time.Now()
+	lastWrites := s.writes.Load()
+	yields := 0
+
 	for {
 		select {
-		case <-ticker.C:
+		case now := <-ticker.C:
+			writes := s.writes.Load()
+			yield := s.yieldSweep(writes-lastWrites, now.Sub(lastTick), yields)
+			lastTick, lastWrites = now, writes
+			if yield {
+				yields++
+				interval = baseInterval
+				ticker.Reset(interval)
+				continue
+			}
+			yields = 0
+
 			expiredCount := s.cleanExpired()
 			if expiredCount == 0 {
 				interval *= 2
@@ -111,57 +631,51 @@ func (s *NodeShards) startCleaner() {
 }
 
 // cleanExpired checks for expired nodes in the pool and removes them.
-// It also evicts nodes from the eviction heap if the size exceeds the capacity.
-// Returns the count of expired nodes removed.
+// It also evicts nodes if the size exceeds the capacity.
+// When the shard has a cleaner budget, at most that many expired nodes are removed
+// per call, bounding the time the lock is held; the rest are left for the next sweep.
+// Returns the count of expired nodes removed. The caller must not hold poolMut.
 func (ns *NodeShards) cleanExpired() int {
 	now := time.Now().Unix()
 	expiredCount := 0
 
-	ns.mut.Lock()
-	defer ns.mut.Unlock()
-	for key, node := range ns.pool {
-		if node.expiredAt > 0 && node.expiredAt <= now {
-			ns.removeNode(node)
-			delete(ns.pool, key)
+	ns.poolMut.RLock()
+	ns.lock()
+	defer func() {
+		pending := ns.takePending()
+		ns.unlock()
+		ns.poolMut.RUnlock()
+		if len(pending) > 0 {
+			ns.dispose(pending)
+		}
+	}()
+	for _, node := range ns.pool {
+		if ns.cleanerBudget > 0 && expiredCount >= ns.cleanerBudget {
+			break
+		}
+		if ns.stale(node, now) {
+			ns.deleteNode(node, removalExpired)
 			expiredCount++
 		}
 	}
 
-	if ns.evictionHeap == nil {
-		return expiredCount
-	}
-
 	for ns.size > ns.capacity {
-		evictedNode := heap.Pop(ns.evictionHeap)
-		if evictedNode != nil {
-			delete(ns.pool, evictedNode.(*Nodes).Key)
+		if ns.evict() == nil {
+			break
 		}
 	}
 	return expiredCount
 }
 
-// moveToTail moves the last node in the linked list to the tail.
-// It removes the node from the tail and updates the eviction heap.
-func (shard *NodeShards) moveToTail() {
-	if shard.tail.prev != shard.head {
-		nodeToEvict := shard.tail.prev
-		shard.removeFromTail(nodeToEvict)
-		shard.evictionHeap.Pop()
-	}
-}
-
-// removeFromTail removes a node from the tail of the linked list.
+// removeFromList unlinks a node from the linked list, wherever it sits.
 // It updates the pointers of the surrounding nodes to maintain the linked list structure.
-func (shard *NodeShards) removeFromTail(node *Nodes) {
-	if node.prev == nil {
+func (shard *NodeShards) removeFromList(node *Nodes) {
+	if node.prev == nil || node.next == nil {
 		return
 	}
 
-	if node.prev.next != nil && shard.tail != nil {
-		node.prev.next = shard.tail
-	}
-
-	if shard.tail.prev != nil && node.prev != nil {
-		shard.tail.prev = node.prev
-	}
+	node.prev.next = node.next
+	node.next.prev = node.prev
+	node.prev = nil
+	node.next = nil
 }
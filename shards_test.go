@@ -0,0 +1,206 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestRemoveBulkMovesClockHand bulk removes the node the clock hand rests on
+// and checks that later evictions still keep the shard consistent.
+func TestRemoveBulkMovesClockHand(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, Policy: PolicyClock})
+	defer m.Close()
+
+	for i := 0; i < 5; i++ {
+		m.Set(fmt.Sprintf("a%d", i), i, 1)
+	}
+	for i := 0; i < 5; i++ {
+		m.Set(fmt.Sprintf("b%d", i), i, 1)
+	}
+	// Evicts a0 and leaves the hand on a1.
+	m.Set("c0", 0, 1)
+
+	if n := m.RemovePrefix("a"); n != 4 {
+		t.Fatalf("RemovePrefix(a) = %d, want 4", n)
+	}
+	for i := 1; i < 10; i++ {
+		m.Set(fmt.Sprintf("c%d", i), i, 1)
+		if err := m.Verify(); err != nil {
+			t.Fatalf("after c%d: %v", i, err)
+		}
+	}
+	if n := m.Len(); n != 10 {
+		t.Fatalf("Len() = %d, want 10", n)
+	}
+}
+
+// TestSetShardCapacityEvictsOnShrink shrinks a full shard and checks that
+// its least recently used entries are evicted until it fits.
+func TestSetShardCapacityEvictsOnShrink(t *testing.T) {
+	evicted := 0
+	m := New(&Config{ShardCap: 1, NodeCap: 10, OnEvict: func(string, interface{}) { evicted++ }})
+	defer m.Close()
+	for i := 0; i < 10; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+
+	if err := m.SetShardCapacity(0, 4); err != nil {
+		t.Fatal(err)
+	}
+	if c := m.ShardCapacity(0); c != 4 {
+		t.Fatalf("ShardCapacity(0) = %d, want 4", c)
+	}
+	if n := m.Len(); n != 4 || evicted != 6 {
+		t.Fatalf("Len() = %d with %d evictions, want 4 and 6", n, evicted)
+	}
+	for i := 6; i < 10; i++ {
+		if _, ok := m.Peek(fmt.Sprintf("key%d", i)); !ok {
+			t.Errorf("key%d was evicted, want the oldest keys evicted", i)
+		}
+	}
+
+	if err := m.SetShardCapacity(1, 4); err != ErrShardIndex {
+		t.Errorf("SetShardCapacity(1) = %v, want ErrShardIndex", err)
+	}
+	if err := m.SetShardCapacity(0, 0); err != ErrInvalidCapacity {
+		t.Errorf("SetShardCapacity(0, 0) = %v, want ErrInvalidCapacity", err)
+	}
+	if c := m.ShardCapacity(1); c != 0 {
+		t.Errorf("ShardCapacity(1) = %d, want 0", c)
+	}
+}
+
+// TestShardBytes checks that ShardBytes reports the cost held by each shard.
+func TestShardBytes(t *testing.T) {
+	m := New(&Config{ShardCap: 3, NodeCap: 10, ShardFunc: func(key string, n int) int {
+		return int(key[0] - '0')
+	}})
+	defer m.Close()
+	m.Set("0a", 1, 5)
+	m.Set("0b", 2, 7)
+	m.Set("2a", 3, 4)
+
+	got := m.ShardBytes()
+	want := []uint64{12, 0, 4}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("ShardBytes() = %v, want %v", got, want)
+	}
+}
+
+// TestCleanerBudget expires many entries and checks that each sweep removes
+// at most CleanerBudget of them, until none are left.
+func TestCleanerBudget(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 100, CleanerBudget: 10})
+	defer m.Close()
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("key%d", i)
+		m.SetTTL(key, i, 1, time.Hour)
+		withNode(t, m, key, func(node *Nodes) { node.expiredAt = 1 })
+	}
+	m.Set("live", 0, 1)
+
+	shard := m.pool[0]
+	for i, want := range []int{10, 10, 5, 0} {
+		if n := shard.cleanExpired(); n != want {
+			t.Fatalf("sweep %d removed %d entries, want %d", i, n, want)
+		}
+	}
+	if n := m.Len(); n != 1 {
+		t.Fatalf("Len() = %d after the sweeps, want the live entry only", n)
+	}
+}
+
+// TestCleanerYieldUnderLoad checks that a yielding cleaner skips sweeps of a
+// shard written faster than cleanerYieldRate, but no more than
+// cleanerMaxYields in a row, and that a cleaner that does not yield never
+// skips.
+func TestCleanerYieldUnderLoad(t *testing.T) {
+	busy := uint64(cleanerYieldRate * 10)
+	quiet := uint64(cleanerYieldRate / 2)
+
+	yielding := &NodeShards{cleanerYield: true}
+	for yields := 0; yields < cleanerMaxYields; yields++ {
+		if !yielding.yieldSweep(busy, time.Second, yields) {
+			t.Fatalf("busy shard swept after %d skips, want it skipped", yields)
+		}
+	}
+	if yielding.yieldSweep(busy, time.Second, cleanerMaxYields) {
+		t.Fatalf("busy shard skipped after %d skips, want it swept regardless", cleanerMaxYields)
+	}
+	if yielding.yieldSweep(quiet, time.Second, 0) {
+		t.Fatal("quiet shard skipped, want it swept")
+	}
+
+	if (&NodeShards{}).yieldSweep(busy, time.Second, 0) {
+		t.Fatal("busy shard skipped without CleanerYieldUnderLoad")
+	}
+}
+
+// TestShardFunc checks that ShardFunc places keys in the shard it returns,
+// wrapping results outside the pool into range.
+func TestShardFunc(t *testing.T) {
+	want := map[string]int{"zero": 0, "two": 2, "five": 1, "minus": 3}
+	m := New(&Config{ShardCap: 4, NodeCap: 10, ShardFunc: func(key string, n int) int {
+		switch key {
+		case "two":
+			return 2
+		case "five":
+			return 5
+		case "minus":
+			return -1
+		}
+		return 0
+	}})
+	defer m.Close()
+
+	for key, shard := range want {
+		m.Set(key, shard, 1)
+		if info, ok := m.Inspect(key); !ok || info.Shard != shard {
+			t.Errorf("%s placed in shard %d, want %d", key, info.Shard, shard)
+		}
+		if _, ok := m.pool[shard].pool[key]; !ok {
+			t.Errorf("shard %d does not hold %s", shard, key)
+		}
+	}
+}
+
+// TestEvictHysteresis overflows a shard and checks that it is brought down to
+// its low water mark in one batch, that the following writes evict nothing
+// until it is full again, and that at least one entry is always kept.
+func TestEvictHysteresis(t *testing.T) {
+	for _, tc := range []struct {
+		nodeCap    int
+		hysteresis float64
+		low        int
+	}{
+		{20, 0, 20},
+		{20, 0.1, 18},
+		{20, 0.99, 1},
+		{2, 0.9, 1},
+	} {
+		m := New(&Config{ShardCap: 1, NodeCap: tc.nodeCap, EvictHysteresis: tc.hysteresis})
+		for i := 0; i <= tc.nodeCap; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, 1)
+		}
+		if n := m.Len(); n != tc.low {
+			t.Errorf("hysteresis %v: Len() = %d after overflowing %d, want %d", tc.hysteresis, n, tc.nodeCap, tc.low)
+		}
+		evictions := m.Stats().Evictions
+		for i := tc.low; i < tc.nodeCap; i++ {
+			m.Set(fmt.Sprintf("more%d", i), i, 1)
+		}
+		if e := m.Stats().Evictions; e != evictions {
+			t.Errorf("hysteresis %v: %d evictions refilling the headroom, want none", tc.hysteresis, e-evictions)
+		}
+		if _, ok := m.Peek(fmt.Sprintf("key%d", tc.nodeCap)); !ok {
+			t.Errorf("hysteresis %v: the overflowing write was evicted", tc.hysteresis)
+		}
+		m.Close()
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "container/list"
+
+// SimulateCapacity estimates the hit rate the cache would have had if it could
+// hold newCap entries in total, by replaying the operations kept in the access
+// log against a shadow LRU of that capacity. Sets insert or refresh a key, Gets
+// count as hits if the key is held and promote it, and Removes drop it. The
+// shadow is a single LRU rather than a sharded one, so the estimate ignores
+// uneven shard load and is most accurate for well-spread keys.
+// The result is the fraction of replayed Gets that hit, in the range 0 to 1.
+// It returns 0 if the access log is disabled, holds no Gets, or newCap is not
+// positive. Enable a large enough Config.AccessLogSize to cover the workload.
+func (m *CacheManager) SimulateCapacity(newCap int) float64 {
+	if newCap <= 0 {
+		return 0
+	}
+	return simulateLRU(m.RecentOps(), newCap)
+}
+
+// simulateLRU replays trace against an LRU holding up to capacity keys and
+// returns the fraction of Gets that hit.
+func simulateLRU(trace []OpRecord, capacity int) float64 {
+	order := list.New()
+	held := make(map[string]*list.Element, capacity)
+
+	var gets, hits int
+	for _, rec := range trace {
+		elem, ok := held[rec.Key]
+		switch rec.Op {
+		case OpGet:
+			gets++
+			if ok {
+				hits++
+				order.MoveToFront(elem)
+			}
+		case OpSet:
+			if ok {
+				order.MoveToFront(elem)
+				continue
+			}
+			held[rec.Key] = order.PushFront(rec.Key)
+			if order.Len() > capacity {
+				oldest := order.Back()
+				order.Remove(oldest)
+				delete(held, oldest.Value.(string))
+			}
+		case OpRemove:
+			if ok {
+				order.Remove(elem)
+				delete(held, rec.Key)
+			}
+		}
+	}
+
+	if gets == 0 {
+		return 0
+	}
+	return float64(hits) / float64(gets)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestSimulateCapacity replays a workload cycling over four keys and checks
+// the hit rate estimated for shadow caches too small, just large enough, and
+// larger than the working set, as well as the cases that estimate 0.
+func TestSimulateCapacity(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, AccessLogSize: 100})
+	defer m.Close()
+	for i := 0; i < 4; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	if rate := m.SimulateCapacity(4); rate != 0 {
+		t.Fatalf("SimulateCapacity(4) = %v without Gets, want 0", rate)
+	}
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 4; i++ {
+			m.Get(fmt.Sprintf("key%d", i))
+		}
+	}
+
+	for capacity, want := range map[int]float64{1: 0.25, 3: 0.75, 4: 1, 8: 1, 0: 0, -1: 0} {
+		if rate := m.SimulateCapacity(capacity); rate != want {
+			t.Errorf("SimulateCapacity(%d) = %v, want %v", capacity, rate, want)
+		}
+	}
+
+	// A removed key misses until it is set again.
+	m.Remove("key3")
+	m.Get("key3")
+	if rate, want := m.SimulateCapacity(4), 20.0/21; rate != want {
+		t.Errorf("SimulateCapacity(4) = %v after a Remove, want %v", rate, want)
+	}
+
+	off := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer off.Close()
+	off.Set("a", 1, 1)
+	off.Get("a")
+	if rate := off.SimulateCapacity(4); rate != 0 {
+		t.Errorf("SimulateCapacity(4) = %v without an access log, want 0", rate)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// snapshotEntry is the serialized form of a single cache entry.
+type snapshotEntry struct {
+	Key       string
+	Value     interface{}
+	Size      uint64
+	ExpiredAt int64
+}
+
+// RegisterType records the concrete type of value so that entries holding it
+// can be written by Snapshot and read back by Restore. Snapshots are encoded
+// with encoding/gob, which needs every concrete type stored behind an
+// interface to be registered; RegisterType is a thin wrapper over gob.Register.
+func RegisterType(value interface{}) {
+	gob.Register(value)
+}
+
+// Snapshot writes every live entry, with its size and expiry, to w so that it
+// can later be loaded with Restore. Values that have not been computed yet by
+// SetLazy are skipped. If a value's concrete type was not registered with
+// RegisterType, Snapshot returns an error wrapping ErrUnregisteredType that
+// names the type.
+func (m *CacheManager) Snapshot(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	for _, entry := range m.snapshotEntries() {
+		if err := enc.Encode(&entry); err != nil {
+			if strings.Contains(err.Error(), "type not registered") {
+				return fmt.Errorf("%w: %T stored under key %q; call RegisterType before Snapshot",
+					ErrUnregisteredType, entry.Value, entry.Key)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore loads entries written by Snapshot into the cache, keeping their
+// remaining lifetime. Entries that have expired since the snapshot was taken
+// are skipped.
+func (m *CacheManager) Restore(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	now := time.Now()
+	for {
+		var entry snapshotEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var ttl time.Duration
+		if entry.ExpiredAt > 0 {
+			ttl = time.Unix(entry.ExpiredAt, 0).Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		m.SetTTL(entry.Key, entry.Value, entry.Size, ttl)
+	}
+}
+
+// snapshotEntries collects the live entries of every shard in their decoded form.
+func (m *CacheManager) snapshotEntries() []snapshotEntry {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	var entries []snapshotEntry
+	now := time.Now().Unix()
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		for _, node := range shard.pool {
+			if shard.stale(node, now) {
+				continue
+			}
+			if _, ok := node.Value.(*lazyValue); ok {
+				continue
+			}
+			entries = append(entries, snapshotEntry{
+				Key:       node.Key,
+				Value:     m.decode(node.Value),
+				Size:      node.nodeSize,
+				ExpiredAt: node.expiredAt,
+			})
+		}
+		shard.mut.RUnlock()
+	}
+	return entries
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// unregistered is a value type never passed to RegisterType.
+type unregistered struct{ N int }
+
+// registered is a value type passed to RegisterType by the test.
+type registered struct{ N int }
+
+// TestSnapshotRegisterType checks that Snapshot names an unregistered value
+// type, and that once it is registered the entries survive a round trip
+// through Restore with their value, size and remaining lifetime.
+func TestSnapshotRegisterType(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 10})
+	defer m.Close()
+	m.Set("bad", unregistered{1}, 1)
+	var buf bytes.Buffer
+	if err := m.Snapshot(&buf); !errors.Is(err, ErrUnregisteredType) {
+		t.Fatalf("Snapshot with an unregistered type = %v, want ErrUnregisteredType", err)
+	}
+
+	RegisterType(registered{})
+	m.Remove("bad")
+	m.SetTTL("struct", registered{7}, 5, time.Hour)
+	m.SetTTL("text", "hello", 3, 0)
+	buf.Reset()
+	if err := m.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(&Config{ShardCap: 2, NodeCap: 10})
+	defer restored.Close()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if v := restored.Get("struct"); v != (registered{7}) {
+		t.Errorf("Get(struct) = %#v after Restore, want registered{7}", v)
+	}
+	if v := restored.Get("text"); v != "hello" {
+		t.Errorf("Get(text) = %#v after Restore, want hello", v)
+	}
+	if ttl, ok := restored.TTL("struct"); !ok || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL(struct) = %v, %v after Restore; want about an hour", ttl, ok)
+	}
+	if ttl, _ := restored.TTL("text"); ttl != NoExpiry {
+		t.Errorf("TTL(text) = %v after Restore, want NoExpiry", ttl)
+	}
+	if c := restored.Cost(); c != 8 {
+		t.Errorf("Cost() = %d after Restore, want 8", c)
+	}
+}
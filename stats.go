@@ -0,0 +1,203 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of cache activity.
+type Stats struct {
+	// Hits is the number of reads that found a live entry.
+	Hits uint64
+
+	// Misses is the number of reads that found no live entry.
+	Misses uint64
+
+	// Evictions is the number of entries removed to make room for others,
+	// either because a shard was over capacity or over its cost budget.
+	Evictions uint64
+
+	// Expirations is the number of entries removed because they expired or
+	// were invalidated by BumpEpoch, whether found on access or by the cleaner.
+	Expirations uint64
+
+	// Entries is the number of entries currently held by the cache.
+	Entries int
+
+	// Bytes is the total cost of the entries currently held by the cache.
+	Bytes uint64
+
+	// Goroutines is the number of background goroutines currently run by
+	// the cache, such as shard cleaners and the OnStats report.
+	Goroutines int
+
+	// Shards is the current number of shards in the pool.
+	Shards int
+
+	// EstimateTime is the total time spent in CacheCost, estimating the
+	// size of values written with a size of zero.
+	EstimateTime time.Duration
+
+	// EncodeTime is the total time spent encoding and compressing values
+	// on write, when EncodeValues or compression is enabled. Comparing it
+	// and EstimateTime with the time spent in writes shows whether passing
+	// explicit sizes or storing values unencoded would pay off.
+	EncodeTime time.Duration
+}
+
+// cacheStats holds the cumulative counters behind Stats. It is shared by
+// the CacheManager and all of its shards.
+type cacheStats struct {
+	hits, misses, evictions, expirations atomic.Uint64
+
+	// estimateNanos and encodeNanos back Stats.EstimateTime and EncodeTime.
+	estimateNanos, encodeNanos atomic.Uint64
+
+	// recent counts evictions per second over the last evictionRateWindow
+	// seconds, backing EvictionRate.
+	recent rateRing
+}
+
+// evictionRateWindow is the number of one-second buckets EvictionRate
+// averages over.
+const evictionRateWindow = 10
+
+// rateRing counts events in one-second buckets over a sliding window.
+// Each bucket remembers the second it counts, so buckets left over from
+// earlier laps of the ring are ignored and reset lazily.
+type rateRing struct {
+	mut     sync.Mutex
+	counts  [evictionRateWindow]uint64
+	seconds [evictionRateWindow]int64
+}
+
+// add counts one event at time now, in Unix seconds.
+func (r *rateRing) add(now int64) {
+	r.addN(now, 1)
+}
+
+// addN counts n events at time now, in Unix seconds.
+func (r *rateRing) addN(now int64, n uint64) {
+	i := now % evictionRateWindow
+	r.mut.Lock()
+	if r.seconds[i] != now {
+		r.seconds[i] = now
+		r.counts[i] = 0
+	}
+	r.counts[i] += n
+	r.mut.Unlock()
+}
+
+// reset discards every counted event.
+func (r *rateRing) reset() {
+	r.mut.Lock()
+	r.counts = [evictionRateWindow]uint64{}
+	r.seconds = [evictionRateWindow]int64{}
+	r.mut.Unlock()
+}
+
+// rate returns the average number of events per second over the window
+// ending at now, in Unix seconds.
+func (r *rateRing) rate(now int64) float64 {
+	var total uint64
+	r.mut.Lock()
+	for i, sec := range r.seconds {
+		if sec > now-evictionRateWindow && sec <= now {
+			total += r.counts[i]
+		}
+	}
+	r.mut.Unlock()
+	return float64(total) / evictionRateWindow
+}
+
+// recordEviction counts an eviction.
+func (cs *cacheStats) recordEviction() {
+	cs.evictions.Add(1)
+	cs.recent.add(time.Now().Unix())
+}
+
+// recordEvictions counts n evictions made at once.
+func (cs *cacheStats) recordEvictions(n uint64) {
+	cs.evictions.Add(n)
+	cs.recent.addN(time.Now().Unix(), n)
+}
+
+// recordRead counts a read as a hit or a miss.
+func (cs *cacheStats) recordRead(hit bool) {
+	if hit {
+		cs.hits.Add(1)
+	} else {
+		cs.misses.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the cache's hit, miss, eviction and expiration counters
+// along with the current number of entries and their total cost, and the
+// number of shards and background goroutines.
+func (m *CacheManager) Stats() Stats {
+	stats := Stats{
+		Hits:         m.stats.hits.Load(),
+		Misses:       m.stats.misses.Load(),
+		Evictions:    m.stats.evictions.Load(),
+		Expirations:  m.stats.expirations.Load(),
+		Goroutines:   int(m.goroutines.Load()),
+		EstimateTime: time.Duration(m.stats.estimateNanos.Load()),
+		EncodeTime:   time.Duration(m.stats.encodeNanos.Load()),
+	}
+
+	m.poolMut.RLock()
+	stats.Shards = len(m.pool)
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		stats.Entries += shard.size
+		stats.Bytes += shard.shardSize
+		shard.mut.RUnlock()
+	}
+	m.poolMut.RUnlock()
+
+	return stats
+}
+
+// reportStats delivers a Stats snapshot to fn every interval until the
+// manager's done channel is closed.
+func (m *CacheManager) reportStats(interval time.Duration, fn func(Stats)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fn(m.Stats())
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// EvictionRate returns the average number of evictions per second over the
+// last ten seconds. Unlike the cumulative Stats.Evictions, it falls back to
+// zero once evictions stop; a rate that stays high suggests the cache is too
+// small for its working set.
+func (m *CacheManager) EvictionRate() float64 {
+	return m.stats.recent.rate(time.Now().Unix())
+}
+
+// ResetStats zeroes the cumulative hit, miss, eviction and expiration counters,
+// the estimation and encoding times, and the eviction rate, so that later
+// Stats calls measure a fresh window. The entry, byte, shard and goroutine
+// counts reflect the current state and are unaffected.
+func (m *CacheManager) ResetStats() {
+	m.stats.hits.Store(0)
+	m.stats.misses.Store(0)
+	m.stats.evictions.Store(0)
+	m.stats.expirations.Store(0)
+	m.stats.estimateNanos.Store(0)
+	m.stats.encodeNanos.Store(0)
+	m.stats.recent.reset()
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestOnStats checks that OnStats receives snapshots of the statistics every
+// StatsInterval, and none once the cache is closed.
+func TestOnStats(t *testing.T) {
+	reports := make(chan Stats, 100)
+	m := New(&Config{
+		ShardCap:      1,
+		NodeCap:       10,
+		StatsInterval: 5 * time.Millisecond,
+		OnStats: func(s Stats) {
+			select {
+			case reports <- s:
+			default:
+			}
+		},
+	})
+	m.Set("key", 1, 1)
+	m.Get("key")
+
+	select {
+	case s := <-reports:
+		if s.Hits != 1 || s.Entries != 1 {
+			t.Fatalf("report = %+v, want 1 hit and 1 entry", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no report within a second")
+	}
+
+	m.Close()
+	for len(reports) > 0 {
+		<-reports
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(reports); n != 0 {
+		t.Fatalf("%d reports after Close, want 0", n)
+	}
+}
+
+// TestSizeHistogram checks that SizeHistogram counts live entries in the
+// bucket of their size, bounds included, with larger ones in the last.
+func TestSizeHistogram(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	for i, size := range []uint64{1, 10, 11, 100, 101, 5000} {
+		m.Set(fmt.Sprintf("key%d", i), i, size)
+	}
+	m.Set("expired", 0, 1)
+	withNode(t, m, "expired", func(node *Nodes) { node.expiredAt = time.Now().Unix() - 1 })
+
+	got := m.SizeHistogram([]uint64{10, 100, 1000})
+	if fmt.Sprint(got) != "[2 2 1 1]" {
+		t.Fatalf("SizeHistogram = %v, want [2 2 1 1]", got)
+	}
+}
+
+// TestStatsGoroutinesAndShards checks that Stats reports the background
+// goroutines and the shards of the cache as they change.
+func TestStatsGoroutinesAndShards(t *testing.T) {
+	m := New(&Config{ShardCap: 8, NodeCap: 10, EnableCleaner: true, EnableDynamicSharding: true})
+	if s := m.Stats(); s.Shards != 4 || s.Goroutines != 4 {
+		t.Fatalf("Stats() = %d shards, %d goroutines; want 4 of each", s.Shards, s.Goroutines)
+	}
+
+	m.EnsureShards(6)
+	if s := m.Stats(); s.Shards != 6 || s.Goroutines != 6 {
+		t.Fatalf("Stats() = %d shards, %d goroutines after growing; want 6 of each", s.Shards, s.Goroutines)
+	}
+
+	m.Close()
+	if s := m.Stats(); s.Goroutines != 0 {
+		t.Fatalf("Stats() = %d goroutines after Close, want 0", s.Goroutines)
+	}
+}
+
+// TestEvictionRate evicts entries and checks that EvictionRate averages them
+// over the window, and that ResetStats clears it.
+func TestEvictionRate(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 30; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+	if rate := m.EvictionRate(); rate != 2 {
+		t.Fatalf("EvictionRate() = %v after 20 evictions, want 2", rate)
+	}
+	m.ResetStats()
+	if rate := m.EvictionRate(); rate != 0 {
+		t.Fatalf("EvictionRate() = %v after ResetStats, want 0", rate)
+	}
+}
+
+// TestRateRingWindow checks that the ring only averages the events of the
+// last evictionRateWindow seconds, and that a bucket reused on a later lap
+// forgets its old count.
+func TestRateRingWindow(t *testing.T) {
+	var r rateRing
+	for i := 0; i < 5; i++ {
+		r.add(100)
+		r.add(105)
+	}
+	for now, want := range map[int64]float64{99: 0, 100: 0.5, 105: 1, 109: 1, 110: 0.5, 115: 0} {
+		if rate := r.rate(now); rate != want {
+			t.Errorf("rate(%d) = %v, want %v", now, rate, want)
+		}
+	}
+
+	r.add(110)
+	if rate := r.rate(110); rate != 0.6 {
+		t.Errorf("rate(110) = %v after a new lap, want 0.6", rate)
+	}
+}
+
+// slowCoster is a Coster whose CacheCost takes a known time.
+type slowCoster struct{}
+
+func (slowCoster) CacheCost() uint64 {
+	time.Sleep(5 * time.Millisecond)
+	return 1
+}
+
+// slowCodec is a stringCodec whose Encode takes a known time.
+type slowCodec struct{ stringCodec }
+
+func (c slowCodec) Encode(value interface{}) ([]byte, error) {
+	time.Sleep(5 * time.Millisecond)
+	return c.stringCodec.Encode(value)
+}
+
+// TestEstimateAndEncodeTime checks that Stats reports the time spent in
+// CacheCost and in encoding, that CacheCost is not timed when a size is
+// given, and that ResetStats zeroes both.
+func TestEstimateAndEncodeTime(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, Codec: slowCodec{}, EncodeValues: true})
+	defer m.Close()
+	if s := m.Stats(); s.EstimateTime != 0 || s.EncodeTime != 0 {
+		t.Fatalf("Stats() = %v estimating, %v encoding before any write; want 0", s.EstimateTime, s.EncodeTime)
+	}
+
+	m.Set("given", slowCoster{}, 1)
+	if s := m.Stats(); s.EstimateTime != 0 || s.EncodeTime < 5*time.Millisecond {
+		t.Fatalf("Stats() = %v estimating, %v encoding; want 0 and at least 5ms", s.EstimateTime, s.EncodeTime)
+	}
+	m.Set("estimated", slowCoster{}, 0)
+	if s := m.Stats(); s.EstimateTime < 5*time.Millisecond || s.EncodeTime < 10*time.Millisecond {
+		t.Fatalf("Stats() = %v estimating, %v encoding; want at least 5ms and 10ms", s.EstimateTime, s.EncodeTime)
+	}
+
+	m.ResetStats()
+	if s := m.Stats(); s.EstimateTime != 0 || s.EncodeTime != 0 {
+		t.Fatalf("Stats() = %v estimating, %v encoding after ResetStats; want 0", s.EstimateTime, s.EncodeTime)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "strings"
+
+// pathSeparator separates the segments of hierarchical keys such as "a/b/c".
+const pathSeparator = "/"
+
+// RemoveSubtree deletes every entry whose key is path or lies under it, and
+// returns the number of entries removed. Keys are treated as paths separated
+// by "/", so "a/b" removes "a/b" and "a/b/c" but not "a/bx", unlike
+// RemovePrefix. Trailing separators on path are ignored. Like RemovePrefix,
+// each shard is locked once and its nodes removed in bulk.
+func (m *CacheManager) RemoveSubtree(path string) int {
+	path = strings.TrimRight(path, pathSeparator)
+	under := path + pathSeparator
+	return m.removeMatching(func(key string) bool {
+		return key == path || strings.HasPrefix(key, under)
+	})
+}
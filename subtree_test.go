@@ -0,0 +1,39 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "testing"
+
+// TestRemoveSubtree checks that RemoveSubtree removes a path and the keys
+// under it across shards, but not siblings that merely share its prefix, and
+// that trailing separators make no difference.
+func TestRemoveSubtree(t *testing.T) {
+	m := New(&Config{ShardCap: 4, NodeCap: 10})
+	defer m.Close()
+	keys := []string{"a/b", "a/b/c", "a/b/c/d", "a/b/e", "a/bx", "a/bx/c", "a", "b/a/b"}
+	for i, key := range keys {
+		m.Set(key, i, 1)
+	}
+
+	if n := m.RemoveSubtree("a/b//"); n != 4 {
+		t.Fatalf("RemoveSubtree(a/b//) = %d, want 4", n)
+	}
+	for _, key := range keys {
+		_, ok := m.Peek(key)
+		if want := key == "a/bx" || key == "a/bx/c" || key == "a" || key == "b/a/b"; ok != want {
+			t.Errorf("Peek(%s) = %v, want %v", key, ok, want)
+		}
+	}
+	if n := m.RemoveSubtree("a/b"); n != 0 {
+		t.Fatalf("RemoveSubtree(a/b) = %d on a removed subtree, want 0", n)
+	}
+	if n := m.RemoveSubtree("a"); n != 3 {
+		t.Fatalf("RemoveSubtree(a) = %d, want 3", n)
+	}
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
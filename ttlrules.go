@@ -0,0 +1,41 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"strings"
+	"time"
+)
+
+// TTLRule gives keys starting with Prefix a default TTL. See Config.TTLRules.
+type TTLRule struct {
+	// Prefix is matched against the start of the key.
+	Prefix string
+
+	// TTL is the time-to-live given to matching keys. A non-positive TTL
+	// makes matching keys never expire.
+	TTL time.Duration
+}
+
+// ruleTTL returns the TTL of the first rule matching key, if any.
+func (m *CacheManager) ruleTTL(key string) (time.Duration, bool) {
+	for _, rule := range m.ttlRules {
+		if strings.HasPrefix(key, rule.Prefix) {
+			return rule.TTL, true
+		}
+	}
+	return 0, false
+}
+
+// slide refreshes the expiry of a node of shard that was just read, when
+// sliding TTLs are enabled and the node has a TTL, spreading it like the
+// expiry given on write. The caller must hold the shard lock.
+func (m *CacheManager) slide(shard *NodeShards, node *Nodes) {
+	if m.slidingTTL && node.ttl > 0 {
+		now := time.Now().Unix()
+		node.expiredAt = shard.spread(expiryFor(node.ttl), now)
+	}
+}
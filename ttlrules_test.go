@@ -0,0 +1,208 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// expiries returns the remaining lifetime, in seconds, of each entry of m.
+func expiries(m *CacheManager) []int64 {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	now := time.Now().Unix()
+	var lives []int64
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		for _, node := range shard.pool {
+			lives = append(lives, node.expiredAt-now)
+		}
+		shard.mut.RUnlock()
+	}
+	return lives
+}
+
+// checkSpread checks that lives lie within fraction of ttl around it, and
+// that they are spread across that window rather than bunched together.
+func checkSpread(t *testing.T, lives []int64, ttl time.Duration, fraction float64) {
+	t.Helper()
+	secs := int64(ttl / time.Second)
+	lo := secs - int64(float64(secs)*fraction) - 1
+	hi := secs + int64(float64(secs)*fraction) + 1
+
+	distinct := make(map[int64]bool)
+	min, max := lives[0], lives[0]
+	for _, life := range lives {
+		if life < lo || life > hi {
+			t.Fatalf("lifetime %ds outside [%d, %d]", life, lo, hi)
+		}
+		distinct[life] = true
+		if life < min {
+			min = life
+		}
+		if life > max {
+			max = life
+		}
+	}
+	if len(distinct) < len(lives)/4 {
+		t.Fatalf("%d distinct lifetimes among %d entries, want them spread", len(distinct), len(lives))
+	}
+	if max-min < (hi-lo)/2 {
+		t.Fatalf("lifetimes span %ds of a %ds window, want at least half", max-min, hi-lo)
+	}
+}
+
+// TestTTLJitterSpreadsWrites writes many entries with the same TTL and checks
+// that their expiries are spread across the jitter window.
+func TestTTLJitterSpreadsWrites(t *testing.T) {
+	const ttl = time.Hour
+	m, err := NewWithOptions(WithShardCap(4), WithNodeCap(1000), WithTTLJitter(0.2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	for i := 0; i < 400; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, ttl)
+	}
+	checkSpread(t, expiries(m), ttl, 0.2)
+}
+
+// TestTTLJitterSpreadsSlidingRefreshes writes entries without jitter, then
+// reads them repeatedly under SlidingTTL with jitter, and checks that the
+// refreshed expiries stay spread across the jitter window rather than all
+// moving to the same instant.
+func TestTTLJitterSpreadsSlidingRefreshes(t *testing.T) {
+	const ttl = time.Hour
+	m, err := NewWithOptions(WithShardCap(4), WithNodeCap(1000), WithSlidingTTL(true), WithTTLJitter(0.2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	for i := 0; i < 400; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, ttl)
+	}
+	m.poolMut.RLock()
+	for _, shard := range m.pool {
+		shard.lock()
+		for _, node := range shard.pool {
+			node.expiredAt = expiryFor(ttl)
+		}
+		shard.unlock()
+	}
+	m.poolMut.RUnlock()
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 400; i++ {
+			m.Get(fmt.Sprintf("key%d", i))
+		}
+		checkSpread(t, expiries(m), ttl, 0.2)
+	}
+}
+
+// TestTTLRules checks that Set gives a key the TTL of the first rule it
+// matches, even when a later rule matches too, falls back to the 12 hour default otherwise, and that SetTTL
+// ignores the rules.
+func TestTTLRules(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, TTLRules: []TTLRule{
+		{Prefix: "session:", TTL: time.Minute},
+		{Prefix: "s", TTL: time.Hour},
+		{Prefix: "static:", TTL: 0},
+	}})
+	defer m.Close()
+	m.Set("session:1", 1, 1)
+	m.Set("stat", 2, 1)
+	m.Set("static:1", 3, 1)
+	m.Set("other", 3, 1)
+	m.SetTTL("session:2", 4, 1, 2*time.Hour)
+
+	for key, want := range map[string]time.Duration{
+		"session:1": time.Minute,
+		"stat":      time.Hour,
+		"static:1":  time.Hour,
+		"other":     defaultTTL,
+		"session:2": 2 * time.Hour,
+	} {
+		if ttl, ok := m.TTL(key); !ok || ttl > want || ttl < want-2*time.Second {
+			t.Errorf("TTL(%s) = %v, %v; want %v", key, ttl, ok, want)
+		}
+	}
+}
+
+// TestRequireExplicitTTL checks that Set panics with ErrTTLRequired for a key
+// no rule covers, storing nothing, while covered keys and SetTTL still work.
+func TestRequireExplicitTTL(t *testing.T) {
+	m := New(&Config{
+		ShardCap:           1,
+		NodeCap:            10,
+		RequireExplicitTTL: true,
+		TTLRules:           []TTLRule{{Prefix: "session:", TTL: time.Minute}},
+	})
+	defer m.Close()
+
+	m.Set("session:1", 1, 1)
+	m.SetTTL("other", 2, 1, time.Hour)
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrTTLRequired) {
+				t.Fatalf("Set(bare) panicked with %v, want ErrTTLRequired", err)
+			}
+		}()
+		m.Set("bare", 3, 1)
+	}()
+
+	if _, ok := m.Peek("bare"); ok {
+		t.Fatal("bare was stored despite the panic")
+	}
+	for _, key := range []string{"session:1", "other"} {
+		if _, ok := m.Peek(key); !ok {
+			t.Errorf("%s is missing", key)
+		}
+	}
+}
+
+// TestTTLJitterBounds checks that without jitter every entry gets its exact
+// TTL, and that with jitter entries that never expire are left alone and a
+// short lifetime never lands at or before the time of the write.
+func TestTTLJitterBounds(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 100})
+	for i := 0; i < 50; i++ {
+		m.SetTTL(fmt.Sprintf("key%d", i), i, 1, time.Hour)
+	}
+	for _, life := range expiries(m) {
+		if life < 3599 || life > 3600 {
+			t.Fatalf("lifetime %ds without jitter, want exactly an hour", life)
+		}
+	}
+	m.Close()
+
+	m, err := NewWithOptions(WithShardCap(1), WithNodeCap(10), WithTTLJitter(0.99))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.SetTTL("forever", 1, 1, 0)
+	if ttl, _ := m.TTL("forever"); ttl != NoExpiry {
+		t.Fatalf("TTL(forever) = %v with jitter, want NoExpiry", ttl)
+	}
+	shard := m.pool[0]
+	now := time.Now().Unix()
+	earliest := now + 1
+	shard.lock()
+	for i := 0; i < 1000; i++ {
+		earliest = min(earliest, shard.spread(now+1, now))
+	}
+	shard.unlock()
+	if earliest <= now {
+		t.Fatalf("spread(now+1) gave now%+d, want after now", earliest-now)
+	}
+}
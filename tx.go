@@ -0,0 +1,165 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"strings"
+	"time"
+)
+
+// Tx is a transactional view of a set of keys that live in a single shard,
+// handed to the function passed to Transact. All of its operations happen
+// under the shard lock held for the whole transaction, so other goroutines
+// observe either none or all of the transaction's writes.
+type Tx interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(key string) (interface{}, bool)
+
+	// Set stores val under key. A replaced entry keeps its expiry and a
+	// new entry never expires, as with Merge.
+	Set(key string, val interface{}, size uint64) error
+
+	// Remove deletes the entry stored under key.
+	Remove(key string) error
+}
+
+// hashTag returns the part of key used to choose its shard. If key contains
+// a non-empty section enclosed in braces, such as "{user:42}:profile", only the
+// first such section is hashed, so keys sharing a tag always share a shard.
+// Otherwise the whole key is hashed.
+func hashTag(key string) string {
+	open := strings.IndexByte(key, '{')
+	if open < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[open+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[open+1 : open+1+end]
+}
+
+// Transact runs fn with a Tx scoped to keys, holding the lock of the shard
+// they live in for the duration, so that fn can read and write them
+// consistently. All keys must live in the same shard, which hash tags make
+// possible: keys like "{order:7}:total" and "{order:7}:items" are always
+// placed together. Transact returns ErrCrossShard, without calling fn, if the
+// keys span shards. fn must not call back into the cache or retain tx after
+// returning. Writes of keys still buffered by WriteCoalesceWindow are applied
+// before fn runs, so that tx sees them. Writes made through tx are ignored
+// while the cache is draining.
+func (m *CacheManager) Transact(keys []string, fn func(tx Tx)) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	defer m.reserve(0)
+	m.poolMut.RLock()
+	idx := m.shardIndex(keys[0])
+	scope := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if m.shardIndex(key) != idx {
+			m.poolMut.RUnlock()
+			return ErrCrossShard
+		}
+		scope[key] = struct{}{}
+	}
+
+	shard := m.pool[idx]
+	shard.lock()
+	defer m.unlockKey(shard)
+	for key := range scope {
+		m.applyPending(shard, key)
+	}
+
+	fn(&tx{m: m, shard: shard, scope: scope})
+	return nil
+}
+
+// tx implements Tx on a locked shard.
+type tx struct {
+	m     *CacheManager
+	shard *NodeShards
+	scope map[string]struct{}
+}
+
+// inScope reports whether key is one of the keys the transaction was opened with.
+func (t *tx) inScope(key string) bool {
+	_, ok := t.scope[key]
+	return ok
+}
+
+// Get implements Tx. Keys outside the transaction are reported as missing.
+func (t *tx) Get(key string) (interface{}, bool) {
+	if !t.inScope(key) {
+		return nil, false
+	}
+
+	node, ok := t.shard.lookup(key, time.Now().Unix())
+	t.m.stats.recordRead(ok)
+	if !ok {
+		return nil, false
+	}
+	t.shard.moveToHead(node)
+
+	val := node.Value
+	if lv, isLazy := val.(*lazyValue); isLazy {
+		val, _ = lv.resolve()
+	}
+	return t.m.decode(val), true
+}
+
+// Set implements Tx. It returns ErrKeyNotInTx for keys outside the transaction.
+func (t *tx) Set(key string, val interface{}, size uint64) error {
+	if !t.inScope(key) {
+		return ErrKeyNotInTx
+	}
+	if t.m.draining.Load() {
+		return nil
+	}
+	size = t.m.costOf(val, size)
+	val, size = t.m.encode(key, val, size)
+
+	node, exists := t.shard.lookup(key, time.Now().Unix())
+	if exists && node.readOnly {
+		return ErrReadOnly
+	}
+	if t.m.oversized(size) {
+		if exists {
+			t.shard.deleteNode(node, removalRemoved)
+		}
+		return nil
+	}
+	if exists {
+		if !t.shard.fits(node, size) {
+			return ErrCostExceeded
+		}
+		t.shard.update(node, val, size)
+		return nil
+	}
+	if !t.shard.admit(&Nodes{
+		Key:      key,
+		Value:    val,
+		nodeSize: size,
+	}) {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+// Remove implements Tx. It returns ErrKeyNotInTx for keys outside the transaction.
+func (t *tx) Remove(key string) error {
+	if !t.inScope(key) {
+		return ErrKeyNotInTx
+	}
+	if t.m.draining.Load() {
+		return nil
+	}
+	if node, ok := t.shard.pool[key]; ok {
+		t.shard.deleteNode(node, removalRemoved)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestHashTag checks which part of a key hashTag selects.
+func TestHashTag(t *testing.T) {
+	for key, want := range map[string]string{
+		"{order:7}:total": "order:7",
+		"a{b}c{d}":        "b",
+		"{}key":           "{}key",
+		"no tag":          "no tag",
+		"{open":           "{open",
+	} {
+		if got := hashTag(key); got != want {
+			t.Errorf("hashTag(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+// TestTransactIsAtomic moves units between two hash-tagged keys from many
+// goroutines and checks that no transaction observes or leaves a state where
+// units are lost.
+func TestTransactIsAtomic(t *testing.T) {
+	m := New(&Config{ShardCap: 8, NodeCap: 10})
+	defer m.Close()
+	keys := []string{"{acct}:a", "{acct}:b"}
+	m.Set(keys[0], 100, 1)
+	m.Set(keys[1], 0, 1)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 20; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			from, to := keys[g%2], keys[1-g%2]
+			for i := 0; i < 50; i++ {
+				err := m.Transact(keys, func(tx Tx) {
+					a, _ := tx.Get(from)
+					b, _ := tx.Get(to)
+					if a.(int)+b.(int) != 100 {
+						t.Errorf("transaction saw a total of %d, want 100", a.(int)+b.(int))
+					}
+					if a.(int) > 0 {
+						tx.Set(from, a.(int)-1, 1)
+						tx.Set(to, b.(int)+1, 1)
+					}
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if total := m.Get(keys[0]).(int) + m.Get(keys[1]).(int); total != 100 {
+		t.Fatalf("total = %d after the transfers, want 100", total)
+	}
+}
+
+// TestTransactScope checks that Transact rejects keys spanning shards and
+// that a transaction cannot write keys it was not opened with.
+func TestTransactScope(t *testing.T) {
+	m := New(&Config{ShardCap: 2, NodeCap: 10, ShardFunc: func(key string, n int) int {
+		return int(key[0]-'a') % n
+	}})
+	defer m.Close()
+
+	called := false
+	if err := m.Transact([]string{"a1", "b1"}, func(Tx) { called = true }); err != ErrCrossShard || called {
+		t.Fatalf("Transact across shards = %v with fn called %v; want ErrCrossShard and no call", err, called)
+	}
+
+	err := m.Transact([]string{"a1"}, func(tx Tx) {
+		if err := tx.Set("c1", 1, 1); err != ErrKeyNotInTx {
+			t.Errorf("Set outside the transaction = %v, want ErrKeyNotInTx", err)
+		}
+		if err := tx.Remove("c1"); err != ErrKeyNotInTx {
+			t.Errorf("Remove outside the transaction = %v, want ErrKeyNotInTx", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Peek("c1"); ok {
+		t.Fatal("a key outside the transaction was written")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"reflect"
+	"time"
+)
+
+// Typed is a type-safe view of a CacheManager holding values of type V. It
+// performs the type assertion on reads, so callers never handle interface{}
+// values. Several Typed views may share one CacheManager; a value stored under
+// a key with a different type is reported as missing rather than causing a panic.
+type Typed[V any] struct {
+	m *CacheManager
+}
+
+// NewTyped returns a Typed view of an existing CacheManager.
+func NewTyped[V any](m *CacheManager) *Typed[V] {
+	return &Typed[V]{m: m}
+}
+
+// NewTypedFromConfig creates a CacheManager from cfg, as New does, and returns
+// a Typed view of it.
+func NewTypedFromConfig[V any](cfg *Config) *Typed[V] {
+	return NewTyped[V](New(cfg))
+}
+
+// Manager returns the underlying CacheManager.
+func (t *Typed[V]) Manager() *CacheManager {
+	return t.m
+}
+
+// Set stores val under key. See CacheManager.Set.
+func (t *Typed[V]) Set(key string, val V, size uint64) {
+	t.m.Set(key, val, size)
+}
+
+// SetTTL stores val under key with a time-to-live. See CacheManager.SetTTL.
+func (t *Typed[V]) SetTTL(key string, val V, size uint64, ttl time.Duration) {
+	t.m.SetTTL(key, val, size, ttl)
+}
+
+// Get returns the value stored under key and true, or the zero value of V and
+// false if the key is missing, has expired or holds a value of another type.
+// A stored nil is returned as the zero value and true when V can hold nil.
+func (t *Typed[V]) Get(key string) (V, bool) {
+	var zero V
+	val, ok := t.m.GetOK(key)
+	if !ok {
+		return zero, false
+	}
+	if val == nil {
+		return zero, nilable(reflect.TypeFor[V]())
+	}
+
+	v, ok := val.(V)
+	if !ok {
+		return zero, false
+	}
+	return v, true
+}
+
+// Remove deletes the entry stored under key. See CacheManager.Remove.
+func (t *Typed[V]) Remove(key string) {
+	t.m.Remove(key)
+}
+
+// nilable reports whether values of type typ can be nil.
+func nilable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
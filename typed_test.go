@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTyped stores values through typed views sharing one cache, and checks
+// that each view reads its own type back and reports the other's values, like
+// missing keys, as misses.
+func TestTyped(t *testing.T) {
+	ints := NewTypedFromConfig[int](&Config{ShardCap: 1, NodeCap: 10})
+	defer ints.Manager().Close()
+	strs := NewTyped[string](ints.Manager())
+
+	ints.Set("count", 3, 1)
+	strs.SetTTL("name", "cerebru", 1, time.Hour)
+	if v, ok := ints.Get("count"); !ok || v != 3 {
+		t.Fatalf("ints.Get(count) = %v, %v; want 3, true", v, ok)
+	}
+	if v, ok := strs.Get("name"); !ok || v != "cerebru" {
+		t.Fatalf("strs.Get(name) = %q, %v; want cerebru, true", v, ok)
+	}
+	if v, ok := ints.Get("name"); ok || v != 0 {
+		t.Fatalf("ints.Get(name) = %v, %v; want 0, false", v, ok)
+	}
+	if ttl, ok := strs.Manager().TTL("name"); !ok || ttl <= 59*time.Minute {
+		t.Fatalf("TTL(name) = %v, %v; want the hour it was set with", ttl, ok)
+	}
+
+	ints.Remove("count")
+	if _, ok := ints.Get("count"); ok {
+		t.Fatal("ints.Get(count) found the removed entry")
+	}
+}
+
+// TestTypedNil checks that a stored nil reads as a hit of the zero value for
+// types that can hold nil, and as a miss for those that cannot.
+func TestTypedNil(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	m.Set("nil", nil, 1)
+
+	if v, ok := NewTyped[*int](m).Get("nil"); !ok || v != nil {
+		t.Errorf("Typed[*int].Get(nil) = %v, %v; want nil, true", v, ok)
+	}
+	if v, ok := NewTyped[[]byte](m).Get("nil"); !ok || v != nil {
+		t.Errorf("Typed[[]byte].Get(nil) = %v, %v; want nil, true", v, ok)
+	}
+	if v, ok := NewTyped[int](m).Get("nil"); ok || v != 0 {
+		t.Errorf("Typed[int].Get(nil) = %v, %v; want 0, false", v, ok)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "fmt"
+
+// Verify checks the internal invariants of every shard: the pool map and the
+// linked list must hold the same nodes, the size and cost accounting must
+// match the stored nodes, and, under the heap-ordered policies, the eviction
+// heap must hold every node that can be evicted, in order. Pure TTL nodes
+// stored by SetTTLNoLRU are expected in the pool only, and pinned nodes
+// outside the eviction policy.
+// It returns an error wrapping ErrInvariant for the first violation found,
+// or nil if the cache is consistent. Verify locks each shard in turn and is
+// meant for tests and diagnostics rather than hot paths.
+func (m *CacheManager) Verify() error {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	for i, shard := range m.pool {
+		shard.mut.RLock()
+		err := shard.verify()
+		shard.mut.RUnlock()
+		if err != nil {
+			return fmt.Errorf("%w: shard %d: %v", ErrInvariant, i, err)
+		}
+	}
+	return nil
+}
+
+// verify checks the invariants of a single shard. The caller must hold the
+// shard lock.
+func (ns *NodeShards) verify() error {
+	if ns.size != len(ns.pool) {
+		return fmt.Errorf("size is %d but pool holds %d nodes", ns.size, len(ns.pool))
+	}
+
+	var cost uint64
+	tracked := 0
+	for key, node := range ns.pool {
+		if node.Key != key {
+			return fmt.Errorf("pool key %q holds node for key %q", key, node.Key)
+		}
+		cost += node.nodeSize
+		if !node.noLRU {
+			tracked++
+		}
+	}
+	if cost != ns.shardSize {
+		return fmt.Errorf("shardSize is %d but nodes sum to %d", ns.shardSize, cost)
+	}
+
+	listed := 0
+	prev := ns.head
+	for node := ns.head.next; node != ns.tail; node = node.next {
+		if node == nil {
+			return fmt.Errorf("linked list is broken after %d nodes", listed)
+		}
+		if node.prev != prev {
+			return fmt.Errorf("node %q has a stale prev pointer", node.Key)
+		}
+		if ns.pool[node.Key] != node || node.noLRU {
+			return fmt.Errorf("linked list holds node %q that is not in the pool", node.Key)
+		}
+		listed++
+		if listed > tracked {
+			return fmt.Errorf("linked list holds more nodes than the pool")
+		}
+		prev = node
+	}
+	if listed != tracked {
+		return fmt.Errorf("linked list holds %d nodes but pool holds %d", listed, tracked)
+	}
+
+	if p, ok := ns.evictor.(verifiedPolicy); ok {
+		return p.verify(ns)
+	}
+	return nil
+}
+
+// Orphans returns the keys of nodes that are in a shard's pool but missing
+// from its eviction heap or unreachable from the head of its linked list.
+// Such nodes can never be evicted in order and indicate that the shard's
+// structures have drifted apart. Pure TTL entries stored by SetTTLNoLRU are
+// never reported, and shards whose policy keeps no eviction heap, such as
+// PolicyLRU and PolicyClock, are only checked against their list. Orphans
+// locks each shard in turn and is meant for diagnostics; a consistent cache
+// returns no keys.
+func (m *CacheManager) Orphans() []string {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	var keys []string
+	for _, shard := range m.pool {
+		shard.mut.RLock()
+		keys = append(keys, shard.orphans()...)
+		shard.mut.RUnlock()
+	}
+	return keys
+}
+
+// orphans returns the keys of the shard's orphaned nodes. The caller must
+// hold the shard lock.
+func (ns *NodeShards) orphans() []string {
+	listed := make(map[*Nodes]struct{}, len(ns.pool))
+	for node := ns.head.next; node != nil && node != ns.tail; node = node.next {
+		if _, seen := listed[node]; seen {
+			break
+		}
+		listed[node] = struct{}{}
+	}
+
+	p, verified := ns.evictor.(verifiedPolicy)
+
+	var keys []string
+	for key, node := range ns.pool {
+		if node.noLRU {
+			continue
+		}
+		_, inList := listed[node]
+		inPolicy := !verified || node.pinned || p.tracks(node)
+		if !inList || !inPolicy {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestOrphans detaches a node from its shard's list, and under LFU another
+// from its heap, and checks that Orphans and Verify report them.
+func TestOrphans(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, PolicyLFU} {
+		m := New(&Config{ShardCap: 1, NodeCap: 10, Policy: policy})
+		for i := 0; i < 5; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, 1)
+		}
+		m.SetTTLNoLRU("pure", 0, 1, 0)
+		if orphans := m.Orphans(); len(orphans) != 0 {
+			t.Fatalf("policy %d: Orphans() = %v on a consistent cache, want none", policy, orphans)
+		}
+
+		shard := m.pool[0]
+		want := []string{"key1"}
+		shard.removeFromList(shard.pool["key1"])
+		if hp, ok := shard.evictor.(*heapPolicy); ok {
+			hp.heap.RemoveNode(shard.pool["key3"])
+			want = append(want, "key3")
+		}
+
+		orphans := m.Orphans()
+		if len(orphans) != len(want) {
+			t.Errorf("policy %d: Orphans() = %v, want %v", policy, orphans, want)
+		}
+		for _, key := range want {
+			found := false
+			for _, o := range orphans {
+				found = found || o == key
+			}
+			if !found {
+				t.Errorf("policy %d: Orphans() = %v, missing %s", policy, orphans, key)
+			}
+		}
+		if err := m.Verify(); !errors.Is(err, ErrInvariant) {
+			t.Errorf("policy %d: Verify() = %v, want ErrInvariant", policy, err)
+		}
+		m.Close()
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+// NextVictim returns the key of the entry the shard at index i would evict
+// next under capacity or cost pressure, without evicting it or changing its
+// position: the root of the shard's eviction heap under the heap-ordered
+// policies, the least recently used entry under PolicyLRU, the entry the
+// clock hand would stop at under PolicyClock, and the one Victim returns
+// under a CustomPolicy. Pinned entries are never reported. The entry may have
+// expired without being swept yet. ok is false if i is out of range or the
+// shard holds nothing that can be evicted.
+// NextVictim is meant for debugging eviction decisions; the answer may be
+// stale as soon as the shard lock is released.
+func (m *CacheManager) NextVictim(i int) (key string, ok bool) {
+	m.poolMut.RLock()
+	defer m.poolMut.RUnlock()
+
+	if i < 0 || i >= len(m.pool) {
+		return "", false
+	}
+
+	// The write lock is taken since policies that cannot tell their victim
+	// without changing state, such as custom ones, are asked through Victim.
+	shard := m.pool[i]
+	shard.lock()
+	defer shard.unlock()
+
+	node := shard.peekVictim()
+	if node == nil {
+		return "", false
+	}
+	return node.Key, true
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestNextVictim checks under every built-in policy that NextVictim names the
+// entry the next write over capacity evicts, skipping pinned entries, and that
+// asking does not change the answer.
+func TestNextVictim(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, Policy2Q, PolicyClock, PolicyLFU, PolicyCost} {
+		m := New(&Config{ShardCap: 1, NodeCap: 5, Policy: policy})
+		if key, ok := m.NextVictim(0); ok {
+			t.Errorf("policy %d: NextVictim(0) = %s on an empty shard, want none", policy, key)
+		}
+		for i := 0; i < 5; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, uint64(i+1))
+		}
+		m.Get("key1")
+		m.Pin("key0")
+
+		for round := 0; round < 3; round++ {
+			victim, ok := m.NextVictim(0)
+			if !ok || victim == "key0" {
+				t.Fatalf("policy %d: NextVictim(0) = %s, %v; want an unpinned entry", policy, victim, ok)
+			}
+			if again, _ := m.NextVictim(0); again != victim {
+				t.Fatalf("policy %d: NextVictim(0) = %s, then %s", policy, victim, again)
+			}
+			m.Set(fmt.Sprintf("new%d", round), round, 3)
+			if _, ok := m.Peek(victim); ok {
+				t.Errorf("policy %d: %s survived the write it was named the victim of", policy, victim)
+			}
+			if n := m.Len(); n != 5 {
+				t.Fatalf("policy %d: Len() = %d, want 5", policy, n)
+			}
+		}
+		for _, i := range []int{-1, 1} {
+			if key, ok := m.NextVictim(i); ok {
+				t.Errorf("policy %d: NextVictim(%d) = %s, want none", policy, i, key)
+			}
+		}
+		m.Close()
+	}
+}
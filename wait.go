@@ -0,0 +1,60 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import "context"
+
+// WaitUntilBelow blocks until LoadFactor drops below factor or ctx is done,
+// in which case the context's error is returned. It lets producers apply
+// backpressure when the cache is saturated. Waiters are woken whenever
+// entries are evicted, expire or are removed, and whenever the capacity grows,
+// through SetShardCapacity or shards being added, so no busy polling is done.
+func (m *CacheManager) WaitUntilBelow(ctx context.Context, factor float64) error {
+	m.releaseWaiters.Add(1)
+	defer m.releaseWaiters.Add(-1)
+
+	for {
+		// Take the signal before checking the load so that a release
+		// happening in between is not missed.
+		signal := m.releaseSignal()
+		if m.LoadFactor() < factor {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-signal:
+		}
+	}
+}
+
+// releaseSignal returns a channel that is closed the next time an entry
+// leaves the cache or the capacity changes.
+func (m *CacheManager) releaseSignal() <-chan struct{} {
+	m.releaseMut.Lock()
+	defer m.releaseMut.Unlock()
+
+	if m.released == nil {
+		m.released = make(chan struct{})
+	}
+	return m.released
+}
+
+// signalRelease wakes every goroutine blocked in WaitUntilBelow, so that they
+// check the load again. It is cheap when nobody is waiting.
+func (m *CacheManager) signalRelease() {
+	if m.releaseWaiters.Load() == 0 {
+		return
+	}
+
+	m.releaseMut.Lock()
+	if m.released != nil {
+		close(m.released)
+		m.released = nil
+	}
+	m.releaseMut.Unlock()
+}
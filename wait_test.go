@@ -0,0 +1,123 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestWaitUntilBelow checks that WaitUntilBelow blocks while the cache is
+// loaded, returns once removals bring the load down, and gives up with the
+// context's error.
+func TestWaitUntilBelow(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10})
+	defer m.Close()
+	for i := 0; i < 10; i++ {
+		m.Set(fmt.Sprintf("key%d", i), i, 1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.WaitUntilBelow(ctx, 0.5); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitUntilBelow on a full cache = %v, want DeadlineExceeded", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.WaitUntilBelow(context.Background(), 0.5) }()
+	for i := 0; i < 6; i++ {
+		m.Remove(fmt.Sprintf("key%d", i))
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("WaitUntilBelow = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitUntilBelow still blocked after the load dropped")
+	}
+}
+
+// TestWaitUntilBelowWakesOnCapacity checks that WaitUntilBelow returns when
+// the load drops because the capacity grows rather than because entries
+// leave: through SetShardCapacity, EnsureShards and dynamic sharding.
+func TestWaitUntilBelowWakesOnCapacity(t *testing.T) {
+	// byLetter places keys by their first letter.
+	byLetter := func(key string, n int) int { return int(key[0]-'a') % n }
+	// waitFor starts a wait for a load below half, runs grow, and checks
+	// that the wait returns.
+	waitFor := func(t *testing.T, m *CacheManager, grow func()) {
+		t.Helper()
+		if f := m.LoadFactor(); f < 0.5 {
+			t.Fatalf("LoadFactor() = %v before growing, want at least 0.5", f)
+		}
+		done := make(chan error, 1)
+		go func() { done <- m.WaitUntilBelow(context.Background(), 0.5) }()
+		for m.releaseWaiters.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		grow()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("WaitUntilBelow = %v, want nil", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("WaitUntilBelow still blocked after the capacity grew")
+		}
+	}
+
+	t.Run("SetShardCapacity", func(t *testing.T) {
+		m := New(&Config{ShardCap: 1, NodeCap: 10})
+		defer m.Close()
+		for i := 0; i < 10; i++ {
+			m.Set(fmt.Sprintf("key%d", i), i, 1)
+		}
+		waitFor(t, m, func() {
+			if err := m.SetShardCapacity(0, 100); err != nil {
+				t.Fatal(err)
+			}
+		})
+	})
+
+	t.Run("EnsureShards", func(t *testing.T) {
+		m := New(&Config{ShardCap: 16, NodeCap: 10, EnableDynamicSharding: true, ShardFunc: byLetter})
+		defer m.Close()
+		for i := 0; i < 5; i++ {
+			for _, c := range "abcd" {
+				m.SetTTL(fmt.Sprintf("%c%d", c, i), i, 1, time.Hour)
+			}
+		}
+		waitFor(t, m, func() { m.EnsureShards(16) })
+	})
+
+	t.Run("dynamic sharding", func(t *testing.T) {
+		m := New(&Config{
+			ShardCap:              8,
+			NodeCap:               10,
+			EnableDynamicSharding: true,
+			ShardFunc:             byLetter,
+		})
+		defer m.Close()
+		// Half of the four shards' capacity, with shard 0 nearly full.
+		for i := 0; i < 4; i++ {
+			for _, c := range "bcd" {
+				m.SetTTL(fmt.Sprintf("%c%d", c, i), i, 1, time.Hour)
+			}
+		}
+		for i := 0; i < 8; i++ {
+			m.SetTTL(fmt.Sprintf("a%d", i), i, 1, time.Hour)
+		}
+		// The next write makes scaling add a shard.
+		waitFor(t, m, func() { m.SetTTL("b9", 9, 1, time.Hour) })
+		if n := len(m.ShardSizes()); n != 5 {
+			t.Fatalf("%d shards after the write, want 5", n)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetWithCost stores val under key for ttl with an explicit cost: an arbitrary
+// weight, such as how expensive the value is to recompute, that is counted
+// against MaxCost in place of the size the other writes take. Unlike that size,
+// the cost is kept as given even when the value is stored encoded or
+// compressed, and a value's CacheCost is not consulted. Under PolicyCost the
+// entries with the lowest cost are evicted first, so expensive entries stay
+// the longest; the other policies only use the cost for MaxCost accounting.
+// An entry whose cost exceeds MaxCost is rejected, and any entry previously
+// stored under key is removed. SetWithCost is never coalesced, and discards a
+// write of key still buffered by WriteCoalesceWindow. A ttl of zero or less
+// makes the entry never expire. SetWithCost does nothing while the cache is
+// draining.
+func (m *CacheManager) SetWithCost(key string, val interface{}, cost uint64, ttl time.Duration) {
+	if m.draining.Load() {
+		return
+	}
+	m.dropPending(key)
+	m.logOp(OpSet, key, false)
+	val, _ = m.encode(key, val, cost)
+	m.store(key, val, cost, expiryFor(ttl), ttl, nil)
+}
+
+// SetWeighted behaves like Set, storing val under key with the given size,
+// but also gives the entry an eviction weight: a hint, separate from size,
+// of how valuable the entry is to keep, such as how expensive it is to
+// recompute. Entries with a higher weight are evicted later, while size is
+// still what counts against MaxCost. Weights order eviction under Policy2Q,
+// PolicyLFU and PolicyCost, where the weight replaces the cost PolicyCost
+// would otherwise rank the entry by, and are available to a CustomPolicy
+// through Nodes.Weight. PolicyLRU and PolicyClock evict in recency order
+// only, so under them SetWeighted stores nothing and returns
+// ErrWeightUnsupported. A later Set, SetTTL, SetWithCost or SetMany of key
+// drops the weight. Like Set, SetWeighted is buffered by
+// WriteCoalesceWindow; when it is not, it returns ErrReadOnly if key holds a
+// read-only entry, and ErrCostExceeded if StrictCost cannot make room for it.
+// SetWeighted does nothing while the cache is draining.
+func (m *CacheManager) SetWeighted(key string, val interface{}, size, weight uint64) error {
+	if !m.weighsEntries() {
+		return ErrWeightUnsupported
+	}
+	if m.requireTTL {
+		if _, ok := m.ruleTTL(key); !ok {
+			panic(fmt.Errorf("%w: SetWeighted(%q)", ErrTTLRequired, key))
+		}
+	}
+	if m.coalesce(key, pendingWrite{val: val, size: size, weight: &weight}) {
+		return nil
+	}
+	return m.setDefault(key, val, size, &weight)
+}
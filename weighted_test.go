@@ -0,0 +1,118 @@
+// Copyright (c) 2025 Bluespada <pentingmain@gmail.com>
+//
+// Distribute under MIT License, please read accompanying
+// file copy or read online at https://opensource.org/license/mit
+
+package cerebru
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSetWeightedKeepsHeavyEntry stores a small entry with a high weight and
+// a large one with a low weight, then fills the cache, and checks that the
+// large entry is evicted first under every policy that uses weights.
+func TestSetWeightedKeepsHeavyEntry(t *testing.T) {
+	for _, policy := range []Policy{Policy2Q, PolicyLFU, PolicyCost} {
+		m := New(&Config{ShardCap: 1, NodeCap: 10, Policy: policy})
+		if err := m.SetWeighted("heavy", 1, 1, 100); err != nil {
+			t.Fatalf("policy %d: SetWeighted(heavy) = %v", policy, err)
+		}
+		if err := m.SetWeighted("light", 2, 50, 1); err != nil {
+			t.Fatalf("policy %d: SetWeighted(light) = %v", policy, err)
+		}
+		for i := 0; i < 8; i++ {
+			m.SetWeighted(fmt.Sprintf("filler%d", i), i, 1, 10)
+		}
+
+		m.SetWeighted("new", 3, 1, 10)
+		if _, ok := m.Peek("light"); ok {
+			t.Errorf("policy %d: light survived, want it evicted first", policy)
+		}
+		if _, ok := m.Peek("heavy"); !ok {
+			t.Errorf("policy %d: heavy was evicted", policy)
+		}
+		if err := m.Verify(); err != nil {
+			t.Errorf("policy %d: %v", policy, err)
+		}
+		m.Close()
+	}
+}
+
+// TestSetWeightedUnsupported checks that SetWeighted refuses to store an
+// entry under the policies that ignore weights.
+func TestSetWeightedUnsupported(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, PolicyClock} {
+		m := New(&Config{ShardCap: 1, NodeCap: 10, Policy: policy})
+		if err := m.SetWeighted("key", 1, 1, 100); !errors.Is(err, ErrWeightUnsupported) {
+			t.Errorf("policy %d: SetWeighted = %v, want ErrWeightUnsupported", policy, err)
+		}
+		if _, ok := m.Peek("key"); ok {
+			t.Errorf("policy %d: key was stored", policy)
+		}
+		m.Close()
+	}
+}
+
+// TestSetWeightedCoalesced buffers a SetWeighted within a coalescing window
+// and checks that it is visible to reads, and keeps its weight once applied.
+func TestSetWeightedCoalesced(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, Policy: PolicyCost, WriteCoalesceWindow: time.Hour})
+	defer m.Close()
+
+	m.SetWeighted("key", 1, 1, 5)
+	m.SetWeighted("key", 2, 1, 7)
+	if v := m.Get("key"); v != 2 {
+		t.Fatalf("buffered Get(key) = %v, want 2", v)
+	}
+	if n := shardWrites(m); n != 0 {
+		t.Fatalf("shard written %d times within the window, want 0", n)
+	}
+
+	m.flush(m.coalescer)
+	node := m.pool[0].pool["key"]
+	if node == nil || node.Value != 2 {
+		t.Fatalf("stored node = %v, want value 2", node)
+	}
+	if w, ok := node.Weight(); !ok || w != 7 {
+		t.Fatalf("Weight() = %d, %v; want 7, true", w, ok)
+	}
+}
+
+// TestSetWithCost checks that SetWithCost charges the given cost rather than
+// a value's CacheCost, that PolicyCost evicts the cheapest entries first, and
+// that it replaces a write still buffered for its key.
+func TestSetWithCost(t *testing.T) {
+	m := New(&Config{ShardCap: 1, NodeCap: 10, MaxCost: 20, Policy: PolicyCost, WriteCoalesceWindow: time.Hour})
+	defer m.Close()
+
+	m.Set("cheap", 0, 1)
+	m.SetWithCost("cheap", costly(100), 2, time.Minute)
+	m.SetWithCost("dear", 1, 9, time.Hour)
+	m.SetWithCost("mid", 2, 5, 0)
+	if c := m.Cost(); c != 16 {
+		t.Fatalf("Cost() = %d, want 16", c)
+	}
+	if v := m.Get("cheap"); v != costly(100) {
+		t.Fatalf("Get(cheap) = %v, want the SetWithCost value, not the buffered one", v)
+	}
+	if ttl, ok := m.TTL("mid"); !ok || ttl != NoExpiry {
+		t.Fatalf("TTL(mid) = %v, %v; want a never expiring entry", ttl, ok)
+	}
+
+	m.SetWithCost("new", 3, 6, time.Hour)
+	if _, ok := m.Peek("cheap"); ok {
+		t.Error("cheap survived, want it evicted first")
+	}
+	for _, key := range []string{"dear", "mid", "new"} {
+		if _, ok := m.Peek(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if c := m.Cost(); c != 20 {
+		t.Fatalf("Cost() = %d, want 20", c)
+	}
+}